@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wtran29/spectre/internal/agentapi"
+	"github.com/wtran29/spectre/internal/checkers/sslcheck"
+)
+
+// maxQueuedResults is how many undelivered results are kept while the server is unreachable; the
+// oldest are dropped first
+const maxQueuedResults = 1000
+
+var errUnauthorized = errors.New("unauthorized")
+
+// client talks to the spectre agent api
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+
+	mu    sync.Mutex
+	queue []agentapi.Result
+	// dropped counts the results ever dropped from the front of a full queue, and sending is set
+	// while a flush is posting results
+	dropped int
+	sending bool
+}
+
+func newClient(server, token string) *client {
+	return &client{
+		server: server,
+		token:  token,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// register tells the server the agent has started
+func (c *client) register(ctx context.Context) (agentapi.RegisterResponse, error) {
+	var resp agentapi.RegisterResponse
+	err := c.do(ctx, http.MethodPost, agentapi.RegisterPath, nil, &resp)
+	return resp, err
+}
+
+// services returns the host services assigned to the agent
+func (c *client) services(ctx context.Context) ([]agentapi.Assignment, error) {
+	var resp agentapi.ServicesResponse
+	err := c.do(ctx, http.MethodGet, agentapi.ServicesPath, nil, &resp)
+	if err == nil {
+		// follow the server's defaults, so that checks run here report the same as on the server
+		sslcheck.SetDefaultThresholds(sslcheck.Thresholds{WarningDays: resp.SSLWarningDays, ProblemDays: resp.SSLProblemDays})
+	}
+	return resp.Services, err
+}
+
+// report queues the result of a check and sends it, along with any earlier results that could
+// not be delivered
+func (c *client) report(ctx context.Context, res agentapi.Result) {
+	c.mu.Lock()
+	c.queue = append(c.queue, res)
+	if over := len(c.queue) - maxQueuedResults; over > 0 {
+		c.queue = c.queue[over:]
+		c.dropped += over
+	}
+	c.mu.Unlock()
+
+	c.flush(ctx)
+}
+
+// flush sends the queued results to the server. The lock is not held while posting, so checks
+// that finish meanwhile queue their results without waiting, and the flush in progress sends them
+// once it is done.
+func (c *client) flush(ctx context.Context) {
+	c.mu.Lock()
+	if c.sending {
+		c.mu.Unlock()
+		return
+	}
+	c.sending = true
+	defer func() {
+		c.mu.Lock()
+		c.sending = false
+		c.mu.Unlock()
+	}()
+
+	for len(c.queue) > 0 {
+		results := make([]agentapi.Result, len(c.queue))
+		copy(results, c.queue)
+		dropped := c.dropped
+		c.mu.Unlock()
+
+		var resp agentapi.ResultsResponse
+		err := c.do(ctx, http.MethodPost, agentapi.ResultsPath, agentapi.ResultsRequest{Results: results}, &resp)
+		if err != nil {
+			log.Printf("Could not send %d result(s), will retry: %s", len(results), err)
+			return
+		}
+		if len(resp.Rejected) > 0 {
+			log.Println("Server rejected results for host services", resp.Rejected)
+		}
+
+		// remove the results that were sent, less any dropped from the front of the queue meanwhile
+		c.mu.Lock()
+		if sent := len(results) - (c.dropped - dropped); sent > 0 {
+			c.queue = c.queue[sent:]
+		}
+	}
+	c.mu.Unlock()
+}
+
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "spectre-agent/"+agentVersion)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
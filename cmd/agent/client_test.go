@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/agentapi"
+)
+
+func TestClient_Register(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != agentapi.RegisterPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(agentapi.RegisterResponse{OK: true, AgentID: 4, Name: "office"})
+	}))
+	defer srv.Close()
+
+	reg, err := newClient(srv.URL, "t0ken").register(context.Background())
+	if err != nil || reg.AgentID != 4 || reg.Name != "office" {
+		t.Errorf("expected to register as agent 4, but got %+v and %v", reg, err)
+	}
+
+	_, err = newClient(srv.URL, "wrong").register(context.Background())
+	if !errors.Is(err, errUnauthorized) {
+		t.Errorf("expected errUnauthorized, but got %v", err)
+	}
+}
+
+func TestClient_ReportRetries(t *testing.T) {
+	var mu sync.Mutex
+	up := false
+	var received []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req agentapi.ResultsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, res := range req.Results {
+			received = append(received, res.HostServiceID)
+		}
+		_ = json.NewEncoder(w).Encode(agentapi.ResultsResponse{OK: true, Accepted: len(req.Results)})
+	}))
+	defer srv.Close()
+
+	c := newClient(srv.URL, "token")
+	c.report(context.Background(), agentapi.Result{HostServiceID: 1})
+	c.report(context.Background(), agentapi.Result{HostServiceID: 2})
+	if len(c.queue) != 2 {
+		t.Fatalf("expected two queued results while the server is down, but got %d", len(c.queue))
+	}
+
+	mu.Lock()
+	up = true
+	mu.Unlock()
+	c.flush(context.Background())
+
+	if len(c.queue) != 0 || len(received) != 2 || received[0] != 1 || received[1] != 2 {
+		t.Errorf("expected results 1 and 2 delivered in order, but got %v with %d queued", received, len(c.queue))
+	}
+}
+
+func TestClient_ReportDoesNotWaitForSlowServer(t *testing.T) {
+	arrived := make(chan struct{}, 10)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var batches [][]int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req agentapi.ResultsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var ids []int
+		for _, res := range req.Results {
+			ids = append(ids, res.HostServiceID)
+		}
+		mu.Lock()
+		batches = append(batches, ids)
+		mu.Unlock()
+
+		arrived <- struct{}{}
+		<-release
+		_ = json.NewEncoder(w).Encode(agentapi.ResultsResponse{OK: true})
+	}))
+	defer srv.Close()
+
+	c := newClient(srv.URL, "token")
+	done := make(chan struct{})
+	go func() {
+		c.report(context.Background(), agentapi.Result{HostServiceID: 1})
+		close(done)
+	}()
+	<-arrived
+
+	// a second check finishing while the first post is in flight queues its result straight away
+	reported := make(chan struct{})
+	go func() {
+		c.report(context.Background(), agentapi.Result{HostServiceID: 2})
+		close(reported)
+	}()
+	select {
+	case <-reported:
+	case <-time.After(2 * time.Second):
+		t.Fatal("report blocked behind the post in flight")
+	}
+
+	// the flush in flight sends it once the first post is done
+	close(release)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 || len(batches[0]) != 1 || batches[0][0] != 1 || len(batches[1]) != 1 || batches[1][0] != 2 {
+		t.Errorf("expected batches [1] and [2], but got %v", batches)
+	}
+	if len(c.queue) != 0 {
+		t.Errorf("expected an empty queue, but got %d results", len(c.queue))
+	}
+}
+
+func TestClient_QueueLimit(t *testing.T) {
+	c := newClient("http://127.0.0.1:0", "token")
+
+	// while a post is in flight, reports only queue their results
+	c.sending = true
+	for i := 1; i <= maxQueuedResults+5; i++ {
+		c.report(context.Background(), agentapi.Result{HostServiceID: i})
+	}
+
+	if len(c.queue) != maxQueuedResults || c.queue[0].HostServiceID != 6 || c.dropped != 5 {
+		t.Errorf("expected the oldest 5 results dropped, but got %d queued starting at %d", len(c.queue), c.queue[0].HostServiceID)
+	}
+}
+
+func TestClient_FlushWhileQueueFull(t *testing.T) {
+	var c *client
+	var batches [][]int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req agentapi.ResultsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var ids []int
+		for _, res := range req.Results {
+			ids = append(ids, res.HostServiceID)
+		}
+		batches = append(batches, ids)
+
+		// checks that finish while the first batch is posted push its oldest results out of the
+		// full queue
+		if len(batches) == 1 {
+			for i := 1; i <= 3; i++ {
+				c.report(context.Background(), agentapi.Result{HostServiceID: maxQueuedResults + i})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(agentapi.ResultsResponse{OK: true})
+	}))
+	defer srv.Close()
+
+	c = newClient(srv.URL, "token")
+	for i := 1; i <= maxQueuedResults; i++ {
+		c.queue = append(c.queue, agentapi.Result{HostServiceID: i})
+	}
+	c.flush(context.Background())
+
+	if len(batches) != 2 || len(batches[0]) != maxQueuedResults || len(batches[1]) != 3 || batches[1][0] != maxQueuedResults+1 {
+		t.Errorf("expected the full queue and then the 3 later results, but got batches of %v", batchSizes(batches))
+	}
+	if len(c.queue) != 0 {
+		t.Errorf("expected an empty queue, but got %d results", len(c.queue))
+	}
+}
+
+func batchSizes(batches [][]int) []int {
+	var sizes []int
+	for _, b := range batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
@@ -0,0 +1,106 @@
+// Command agent runs spectre checks from inside a private network. It registers with the spectre
+// server using the token created on the Agents page, pulls the host services of the hosts assigned
+// to it, runs them on their schedules with the same checkers as the server, and posts the results
+// back.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers/execcheck"
+	"github.com/wtran29/spectre/internal/checkers/httpcheck"
+)
+
+const agentVersion = "1.0.0"
+
+func main() {
+	server := flag.String("server", "http://localhost:4000", "url of the spectre server")
+	token := flag.String("token", os.Getenv("SPECTRE_AGENT_TOKEN"), "agent token (defaults to SPECTRE_AGENT_TOKEN)")
+	refresh := flag.Duration("refresh", time.Minute, "how often to pull assigned host services from the server")
+	execAllowlist := flag.String("execAllowlist", "", "file listing the commands exec checks may run, one per line")
+	probeProxy := flag.String("probeProxy", "", "proxy url for http checks (defaults to HTTP_PROXY/HTTPS_PROXY)")
+	flag.Parse()
+
+	if *token == "" {
+		fmt.Println("Missing agent token.")
+		os.Exit(1)
+	}
+
+	err := httpcheck.Configure(httpcheck.TransportOptions{
+		Proxy:           *probeProxy,
+		MaxConnsPerHost: 10,
+		MaxIdleConns:    100,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the allowlist is kept on the agent, so the server alone can't make it run arbitrary commands
+	if *execAllowlist != "" {
+		b, err := os.ReadFile(*execAllowlist)
+		if err != nil {
+			log.Fatal(err)
+		}
+		execcheck.SetAllowlist(execcheck.ParseAllowlist(string(b)))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := newClient(strings.TrimRight(*server, "/"), *token)
+
+	log.Printf("Spectre agent v%s connecting to %s", agentVersion, *server)
+	for {
+		reg, err := c.register(ctx)
+		if err == nil {
+			log.Printf("Registered as agent %q (id %d)", reg.Name, reg.AgentID)
+			break
+		}
+		if errors.Is(err, errUnauthorized) {
+			log.Fatal("The server rejected the agent token")
+		}
+		log.Println("Could not register, retrying:", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(15 * time.Second):
+		}
+	}
+
+	s := newScheduler(ctx, c)
+	s.start()
+	defer s.stop()
+
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	for {
+		services, err := c.services(ctx)
+		switch {
+		case errors.Is(err, errUnauthorized):
+			log.Fatal("The server rejected the agent token")
+		case err != nil:
+			log.Println("Could not get assigned services:", err)
+		default:
+			s.sync(services)
+		}
+
+		// retry any results that could not be delivered
+		c.flush(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping agent")
+			return
+		case <-ticker.C:
+		}
+	}
+}
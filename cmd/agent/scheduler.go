@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/wtran29/spectre/internal/agentapi"
+	"github.com/wtran29/spectre/internal/checkers"
+	_ "github.com/wtran29/spectre/internal/checkers/certfilecheck" // registers Certificate Files
+	_ "github.com/wtran29/spectre/internal/checkers/dnscheck"      // registers DNS
+	_ "github.com/wtran29/spectre/internal/checkers/execcheck"     // registers Exec
+	_ "github.com/wtran29/spectre/internal/checkers/httpcheck"     // registers HTTP and HTTPS
+	_ "github.com/wtran29/spectre/internal/checkers/pingcheck"     // registers Ping
+	_ "github.com/wtran29/spectre/internal/checkers/sslcheck"      // registers SSL Certificate
+	_ "github.com/wtran29/spectre/internal/checkers/tcpcheck"      // registers TCP
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// scheduler runs the checks of the host services assigned to the agent on their schedules
+type scheduler struct {
+	ctx    context.Context
+	client *client
+	cron   *cron.Cron
+
+	mu      sync.Mutex
+	entries map[int]entry
+}
+
+// entry is a scheduled host service; signature changes whenever the check needs rescheduling.
+// assignment is the latest one synced, so a check sees state the server saved since it was
+// scheduled, such as the certificate an SSL check last found.
+type entry struct {
+	id         cron.EntryID
+	signature  string
+	assignment agentapi.Assignment
+}
+
+func newScheduler(ctx context.Context, c *client) *scheduler {
+	return &scheduler{
+		ctx:    ctx,
+		client: c,
+		cron: cron.New(cron.WithChain(
+			cron.DelayIfStillRunning(cron.DefaultLogger),
+			cron.Recover(cron.DefaultLogger),
+		)),
+		entries: make(map[int]entry),
+	}
+}
+
+func (s *scheduler) start() {
+	s.cron.Start()
+}
+
+func (s *scheduler) stop() {
+	<-s.cron.Stop().Done()
+}
+
+// sync schedules newly assigned host services, reschedules changed ones and drops any that are no
+// longer assigned
+func (s *scheduler) sync(assignments []agentapi.Assignment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int]bool)
+	for _, a := range assignments {
+		hs := a.HostService
+		seen[hs.ID] = true
+
+		spec := schedule(hs)
+		sig := signature(spec, a)
+		if e, ok := s.entries[hs.ID]; ok {
+			if e.signature == sig {
+				e.assignment = a
+				s.entries[hs.ID] = e
+				continue
+			}
+			s.cron.Remove(e.id)
+			delete(s.entries, hs.ID)
+		}
+
+		hostServiceID := hs.ID
+		id, err := s.cron.AddFunc(spec, func() { s.runAssigned(hostServiceID) })
+		if err != nil {
+			log.Println("Could not schedule", hs.Service.ServiceName, "on", a.Host.HostName, ":", err)
+			continue
+		}
+		s.entries[hs.ID] = entry{id: id, signature: sig, assignment: a}
+		log.Println("Scheduled", hs.Service.ServiceName, "on", a.Host.HostName, spec)
+	}
+
+	for id, e := range s.entries {
+		if !seen[id] {
+			s.cron.Remove(e.id)
+			delete(s.entries, id)
+			log.Println("Unscheduled host service", id)
+		}
+	}
+}
+
+// runAssigned checks a host service as it was last synced, unless it has since been unassigned
+func (s *scheduler) runAssigned(hostServiceID int) {
+	s.mu.Lock()
+	e, ok := s.entries[hostServiceID]
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	s.run(e.assignment.Host, e.assignment.HostService)
+}
+
+// run checks a host service and reports the result to the server
+func (s *scheduler) run(h models.Host, hs models.HostService) {
+	res := checkers.Run(s.ctx, h, hs)
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	s.client.report(s.ctx, agentapi.Result{
+		HostServiceID: hs.ID,
+		Status:        res.Status,
+		Message:       res.Message,
+		Metrics:       res.Metrics,
+		State:         res.State,
+		Notices:       res.Notices,
+		Certificates:  res.Certificates,
+		CheckedAt:     time.Now(),
+	})
+}
+
+// schedule returns the cron spec for a host service, as the server builds it: the retry interval
+// while the server is confirming a status change, and otherwise the schedule
+func schedule(hs models.HostService) string {
+	if _, interval := checkers.Retries(hs); hs.Attempts > 0 && interval > 0 {
+		return fmt.Sprintf("@every %s", interval)
+	}
+	if hs.ScheduleUnit == "d" {
+		return fmt.Sprintf("@every %d%s", hs.ScheduleNumber*24, "h")
+	}
+	return fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit)
+}
+
+// signature identifies everything about an assignment that affects how it is checked
+func signature(spec string, a agentapi.Assignment) string {
+	b, _ := json.Marshal(struct {
+		Spec    string
+		Host    models.Host
+		Service string
+		Config  string
+	}{spec, a.Host, a.HostService.Service.ServiceName, a.HostService.Config})
+	return string(b)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/wtran29/spectre/internal/agentapi"
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// stateChecker records the check state of each host service it checks
+type stateChecker struct {
+	mu     sync.Mutex
+	states []string
+}
+
+func (c *stateChecker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states = append(c.states, hs.CheckState)
+	return checkers.Result{Status: "healthy"}
+}
+
+var testChecker = &stateChecker{}
+
+func init() {
+	checkers.Register("Agent State", testChecker)
+}
+
+// resultsServer accepts results as the agent api would, and keeps them
+type resultsServer struct {
+	mu      sync.Mutex
+	results []agentapi.Result
+}
+
+func (s *resultsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req agentapi.ResultsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.results = append(s.results, req.Results...)
+	s.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(agentapi.ResultsResponse{OK: true, Accepted: len(req.Results)})
+}
+
+func assignment(config, state string) agentapi.Assignment {
+	return agentapi.Assignment{
+		Host: models.Host{ID: 1, HostName: "web"},
+		HostService: models.HostService{
+			ID:             5,
+			HostID:         1,
+			ScheduleNumber: 3,
+			ScheduleUnit:   "m",
+			Config:         config,
+			CheckState:     state,
+			Service:        models.Services{ServiceName: "Agent State"},
+		},
+	}
+}
+
+func TestScheduler_Sync(t *testing.T) {
+	srv := httptest.NewServer(&resultsServer{})
+	defer srv.Close()
+	s := newScheduler(context.Background(), newClient(srv.URL, "token"))
+	testChecker.mu.Lock()
+	testChecker.states = nil
+	testChecker.mu.Unlock()
+
+	s.sync([]agentapi.Assignment{assignment("{}", "old-thumbprint")})
+	first := s.entries[5].id
+
+	// the server saved new state: the job keeps its schedule, but checks with the new state
+	s.sync([]agentapi.Assignment{assignment("{}", "new-thumbprint")})
+	if s.entries[5].id != first {
+		t.Error("expected a change of state alone not to reschedule the check")
+	}
+	s.cron.Entry(first).Job.Run()
+
+	testChecker.mu.Lock()
+	states := testChecker.states
+	testChecker.mu.Unlock()
+	if len(states) != 1 || states[0] != "new-thumbprint" {
+		t.Errorf("expected the check to see state new-thumbprint, but got %v", states)
+	}
+
+	// a change of config reschedules the check
+	s.sync([]agentapi.Assignment{assignment(`{"timeout_seconds": 5}`, "new-thumbprint")})
+	if s.entries[5].id == first {
+		t.Error("expected a change of config to reschedule the check")
+	}
+	if len(s.cron.Entries()) != 1 {
+		t.Errorf("expected one scheduled check, but got %d", len(s.cron.Entries()))
+	}
+
+	// and a host service that is no longer assigned is dropped
+	s.sync(nil)
+	if len(s.entries) != 0 || len(s.cron.Entries()) != 0 {
+		t.Errorf("expected no scheduled checks, but got %d", len(s.cron.Entries()))
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	var tests = []struct {
+		name     string
+		hs       models.HostService
+		expected string
+	}{
+		{"minutes", models.HostService{ScheduleNumber: 3, ScheduleUnit: "m"}, "@every 3m"},
+		{"days", models.HostService{ScheduleNumber: 2, ScheduleUnit: "d"}, "@every 48h"},
+		{"confirming", models.HostService{ScheduleNumber: 3, ScheduleUnit: "m", Attempts: 1, Config: `{"retries": 2, "retry_seconds": 20}`}, "@every 20s"},
+		{"not-confirming", models.HostService{ScheduleNumber: 3, ScheduleUnit: "m", Config: `{"retries": 2, "retry_seconds": 20}`}, "@every 3m"},
+	}
+
+	for _, e := range tests {
+		if got := schedule(e.hs); got != e.expected {
+			t.Errorf("%s: expected %s, but got %s", e.name, e.expected, got)
+		}
+	}
+}
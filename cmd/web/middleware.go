@@ -50,6 +50,8 @@ func NoSurf(next http.Handler) http.Handler {
 
 	csrfHandler.ExemptPath("/pusher/auth")
 	csrfHandler.ExemptPath("/pusher/hook")
+	csrfHandler.ExemptRegexp("^/ping/.*")
+	csrfHandler.ExemptRegexp("^/agent/api/.*")
 
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
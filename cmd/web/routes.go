@@ -23,6 +23,23 @@ func routes() http.Handler {
 
 	mux.Get("/user/logout", handlers.Repo.Logout)
 
+	// heartbeat pings from monitored jobs, authenticated by the secret token in the url
+	mux.Get("/ping/{token}", handlers.Repo.Heartbeat)
+	mux.Post("/ping/{token}", handlers.Repo.Heartbeat)
+	mux.Get("/ping/{token}/{result}", handlers.Repo.Heartbeat)
+	mux.Post("/ping/{token}/{result}", handlers.Repo.Heartbeat)
+
+	// certificate expiry calendar feed, authenticated by the secret token in the url
+	mux.Get("/calendar/certificates/{token}", handlers.Repo.CertificateCalendar)
+
+	// api for remote agents, authenticated by agent token
+	mux.Route("/agent/api", func(mux chi.Router) {
+		mux.Use(handlers.Repo.AgentAuth)
+		mux.Post("/register", handlers.Repo.AgentRegister)
+		mux.Get("/services", handlers.Repo.AgentServices)
+		mux.Post("/results", handlers.Repo.AgentResults)
+	})
+
 	mux.Route("/pusher", func(mux chi.Router) {
 		mux.Use(Auth)
 		mux.Post("/auth", handlers.Repo.PusherAuth)
@@ -49,8 +66,11 @@ func routes() http.Handler {
 		// service status pages (all hosts)
 		mux.Get("/all-healthy", handlers.Repo.AllHealthyServices)
 		mux.Get("/all-warning", handlers.Repo.AllWarningServices)
+		mux.Get("/all-unknown", handlers.Repo.AllUnknownServices)
 		mux.Get("/all-problems", handlers.Repo.AllProblemServices)
 		mux.Get("/all-pending", handlers.Repo.AllPendingServices)
+		mux.Get("/all-unreachable", handlers.Repo.AllUnreachableServices)
+		mux.Get("/all-flapping", handlers.Repo.AllFlappingServices)
 
 		// users
 		mux.Get("/users", handlers.Repo.AllUsers)
@@ -58,9 +78,32 @@ func routes() http.Handler {
 		mux.Post("/user/{id}", handlers.Repo.PostOneUser)
 		mux.Get("/user/delete/{id}", handlers.Repo.DeleteUser)
 
+		// remote agents
+		mux.Get("/agents", handlers.Repo.Agents)
+		mux.Post("/agents", handlers.Repo.PostAgent)
+		mux.Get("/agents/delete/{id}", handlers.Repo.DeleteAgent)
+
+		// maintenance windows
+		mux.Get("/maintenance", handlers.Repo.Maintenance)
+		mux.Post("/maintenance", handlers.Repo.PostMaintenance)
+		mux.Get("/maintenance/delete/{id}", handlers.Repo.DeleteMaintenance)
+
+		// notification channels
+		mux.Get("/notifications", handlers.Repo.Notifications)
+		mux.Post("/notifications", handlers.Repo.PostNotificationChannel)
+		mux.Get("/notifications/delete/{id}", handlers.Repo.DeleteNotificationChannel)
+		mux.Post("/notifications/test/{id}", handlers.Repo.TestNotificationChannel)
+		mux.Get("/notifications/deliveries/{id}", handlers.Repo.WebhookDeliveries)
+		mux.Post("/notifications/deliveries/replay/{id}", handlers.Repo.ReplayWebhookDelivery)
+
 		// schedule
 		mux.Get("/schedule", handlers.Repo.ListEntries)
 
+		// certificate inventory
+		mux.Get("/certificates", handlers.Repo.Certificates)
+		mux.Get("/certificates/csv", handlers.Repo.CertificatesCSV)
+		mux.Post("/certificates/calendar-token", handlers.Repo.RegenerateCalendarToken)
+
 		// preferences
 		mux.Post("/preference/ajax/set-system-pref", handlers.Repo.SetSystemPref)
 		mux.Post("/preference/ajax/toggle-monitoring", handlers.Repo.ToggleMonitoring)
@@ -70,6 +113,10 @@ func routes() http.Handler {
 		mux.Get("/host/{id}", handlers.Repo.Host)
 		mux.Post("/host/{id}", handlers.Repo.PostHost)
 		mux.Post("/host/ajax/toggle-service", handlers.Repo.ToggleServiceForHost)
+		mux.Post("/host/ajax/service-config", handlers.Repo.PostHostServiceConfig)
+		mux.Post("/host/ajax/heartbeat-token", handlers.Repo.RegenerateHeartbeatToken)
+		mux.Post("/host/ajax/dependency", handlers.Repo.PostDependency)
+		mux.Post("/host/ajax/remove-dependency", handlers.Repo.RemoveDependency)
 		mux.Get("/perform-check/{id}/{oldStatus}", handlers.Repo.TestCheck)
 	})
 
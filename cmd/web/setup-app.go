@@ -13,10 +13,15 @@ import (
 	"github.com/pusher/pusher-http-go"
 	"github.com/robfig/cron/v3"
 	"github.com/wtran29/spectre/internal/channeldata"
+	"github.com/wtran29/spectre/internal/checkers/execcheck"
+	"github.com/wtran29/spectre/internal/checkers/httpcheck"
+	"github.com/wtran29/spectre/internal/checkers/sslcheck"
 	"github.com/wtran29/spectre/internal/config"
 	"github.com/wtran29/spectre/internal/driver"
 	"github.com/wtran29/spectre/internal/handlers"
 	"github.com/wtran29/spectre/internal/helpers"
+	"github.com/wtran29/spectre/internal/notifiers/smsnotifier"
+	"github.com/wtran29/spectre/internal/notifiers/webhooknotifier"
 )
 
 func setupApp() (*string, error) {
@@ -37,6 +42,10 @@ func setupApp() (*string, error) {
 	pusherKey := flag.String("pusherKey", "", "pusher key")
 	pusherSecret := flag.String("pusherSecret", "", "pusher secret")
 	pusherSecure := flag.Bool("pusherSecure", false, "pusher server uses SSL (true or false)")
+	probeProxy := flag.String("probeProxy", "", "proxy url for http checks (defaults to HTTP_PROXY/HTTPS_PROXY)")
+	probeMaxConns := flag.Int("probeMaxConns", 10, "maximum connections per host for http checks")
+	probeMaxIdle := flag.Int("probeMaxIdle", 100, "maximum idle connections kept for http checks")
+	probeKeepAlive := flag.Bool("probeKeepAlive", false, "reuse connections between http checks")
 
 	flag.Parse()
 
@@ -45,6 +54,16 @@ func setupApp() (*string, error) {
 		os.Exit(1)
 	}
 
+	err := httpcheck.Configure(httpcheck.TransportOptions{
+		Proxy:           *probeProxy,
+		MaxConnsPerHost: *probeMaxConns,
+		MaxIdleConns:    *probeMaxIdle,
+		KeepAlive:       *probeKeepAlive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("Connecting to database....")
 	dsnString := ""
 
@@ -126,6 +145,9 @@ func setupApp() (*string, error) {
 
 	app.PreferenceMap = preferenceMap
 
+	execcheck.SetAllowlist(execcheck.ParseAllowlist(preferenceMap["exec_allowlist"]))
+	sslcheck.SetDefaultThresholds(sslcheck.ParseThresholds(preferenceMap["ssl_warning_days"], preferenceMap["ssl_problem_days"]))
+
 	// create pusher client
 	wsClient = pusher.Client{
 		AppID:  *pusherApp,
@@ -158,6 +180,8 @@ func setupApp() (*string, error) {
 	}
 
 	helpers.NewHelpers(&app)
+	smsnotifier.Configure(&app)
+	webhooknotifier.Configure(repo.DB)
 
 	return insecurePort, err
 }
@@ -0,0 +1,71 @@
+// Package agentapi defines the HTTP api that remote agents use to talk to spectre. An agent
+// authenticates every request with its token in an "Authorization: Bearer <token>" header,
+// registers, pulls the host services assigned to it, and posts back the results of its checks.
+package agentapi
+
+import (
+	"time"
+
+	"github.com/wtran29/spectre/internal/certificateutils"
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// Paths of the agent api, relative to the spectre site url
+const (
+	RegisterPath = "/agent/api/register"
+	ServicesPath = "/agent/api/services"
+	ResultsPath  = "/agent/api/results"
+)
+
+// RegisterResponse is returned when an agent registers
+type RegisterResponse struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+	AgentID int    `json:"agent_id"`
+	Name    string `json:"name"`
+}
+
+// Assignment is a host service that an agent should check, along with its host
+type Assignment struct {
+	Host        models.Host        `json:"host"`
+	HostService models.HostService `json:"host_service"`
+}
+
+// ServicesResponse lists the host services assigned to an agent
+type ServicesResponse struct {
+	OK       bool         `json:"ok"`
+	Message  string       `json:"message"`
+	Services []Assignment `json:"services"`
+	// SSLWarningDays and SSLProblemDays are the server's default certificate expiry thresholds
+	SSLWarningDays int `json:"ssl_warning_days"`
+	SSLProblemDays int `json:"ssl_problem_days"`
+}
+
+// Result is the outcome of one check run by an agent
+type Result struct {
+	HostServiceID int                `json:"host_service_id"`
+	Status        string             `json:"status"`
+	Message       string             `json:"message"`
+	Metrics       map[string]float64 `json:"metrics,omitempty"`
+	State         string             `json:"state,omitempty"`
+	Notices       []checkers.Notice  `json:"notices,omitempty"`
+	// Certificates is null when the check doesn't track certificates, and a list (possibly
+	// empty) when it does
+	Certificates []certificateutils.CertificateDetails `json:"certificates"`
+	CheckedAt    time.Time                             `json:"checked_at"`
+}
+
+// ResultsRequest is posted by an agent with the results of one or more checks
+type ResultsRequest struct {
+	Results []Result `json:"results"`
+}
+
+// ResultsResponse is returned when an agent posts results. Rejected lists results for host
+// services the agent is not (or no longer) assigned.
+type ResultsResponse struct {
+	OK       bool   `json:"ok"`
+	Message  string `json:"message"`
+	Accepted int    `json:"accepted"`
+	Rejected []int  `json:"rejected,omitempty"`
+}
@@ -0,0 +1,83 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/certificateutils"
+	"github.com/wtran29/spectre/internal/checkers"
+)
+
+func TestResultJSON(t *testing.T) {
+	var tests = []struct {
+		name     string
+		result   Result
+		contains []string
+		omits    []string
+	}{
+		{
+			"no-certificates",
+			Result{HostServiceID: 3, Status: "healthy"},
+			[]string{`"host_service_id":3`, `"status":"healthy"`, `"certificates":null`},
+			[]string{`"metrics"`, `"state"`, `"notices"`},
+		},
+		{
+			"tracked-but-none-found",
+			Result{HostServiceID: 3, Status: "problem", Certificates: []certificateutils.CertificateDetails{}},
+			[]string{`"certificates":[]`},
+			nil,
+		},
+		{
+			"with-state-and-notices",
+			Result{HostServiceID: 3, Status: "warning", State: "abc", Metrics: map[string]float64{"latency_ms": 12},
+				Notices: []checkers.Notice{{Type: "certificate-changed", Message: "renewed"}}},
+			[]string{`"state":"abc"`, `"latency_ms":12`, `"certificate-changed"`},
+			nil,
+		},
+	}
+
+	for _, e := range tests {
+		b, err := json.Marshal(e.result)
+		if err != nil {
+			t.Fatalf("%s: %s", e.name, err)
+		}
+		for _, s := range e.contains {
+			if !strings.Contains(string(b), s) {
+				t.Errorf("%s: expected %s in %s", e.name, s, b)
+			}
+		}
+		for _, s := range e.omits {
+			if strings.Contains(string(b), s) {
+				t.Errorf("%s: expected no %s in %s", e.name, s, b)
+			}
+		}
+	}
+}
+
+func TestResultsRoundTrip(t *testing.T) {
+	sent := ResultsRequest{Results: []Result{{
+		HostServiceID: 7,
+		Status:        "healthy",
+		Certificates:  []certificateutils.CertificateDetails{},
+		CheckedAt:     time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+	}}}
+
+	b, err := json.Marshal(sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ResultsRequest
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Results) != 1 {
+		t.Fatalf("expected one result, but got %d", len(got.Results))
+	}
+	r := got.Results[0]
+	if r.HostServiceID != 7 || r.Certificates == nil || !r.CheckedAt.Equal(sent.Results[0].CheckedAt) {
+		t.Errorf("unexpected result after round trip %+v", r)
+	}
+}
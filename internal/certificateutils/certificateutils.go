@@ -5,11 +5,10 @@ import (
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/pem"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
 	"net"
-	"os"
 	"strings"
 	"time"
 )
@@ -36,6 +35,12 @@ type CertificateDetails struct {
 	TimeTaken           time.Duration
 	ExpirationDate      string
 	Thumbprint          string
+	NotAfter            time.Time
+	DNSNames            []string
+	// Source is where the certificate was found: a file path, or the address it was served on
+	Source string
+	// KeyMismatch is set when the private key stored with the certificate does not belong to it
+	KeyMismatch bool
 }
 
 // String returns a formatted string response
@@ -66,55 +71,66 @@ func insertNth(s string, n int) string {
 	return buffer.String()
 }
 
-// ReadCertificateDetailsFromFile reads a cert from disk
+// ReadCertificateDetailsFromFile reads the certificates in a PEM file. If privateCertFile is given,
+// or publicCertFile also holds a private key, KeyMismatch is set on the first certificate when the
+// key does not belong to it.
 func ReadCertificateDetailsFromFile(publicCertFile, privateCertFile string) ([]CertificateDetails, error) {
 	currentTime := time.Now()
 	var certDetails []CertificateDetails
-	var blocks []byte
 
-	rest, err := os.ReadFile(publicCertFile)
+	f, err := ReadPEMFile(publicCertFile)
 	if err != nil {
 		return certDetails, err
 	}
+	if len(f.Certificates) == 0 {
+		return certDetails, errors.New("certificate doesn't have a valid PEM block")
+	}
 
-	for {
-		var block *pem.Block
-		block, rest = pem.Decode(rest)
-
-		if block == nil {
-			return certDetails, errors.New("certificate doesn't have a valid PEM block")
+	key := f.Key
+	if privateCertFile != "" {
+		kf, err := ReadPEMFile(privateCertFile)
+		if err != nil {
+			return certDetails, err
 		}
-
-		blocks = append(blocks, block.Bytes...)
-		if len(rest) == 0 {
-			break
+		if kf.Key == nil {
+			return certDetails, fmt.Errorf("%s holds no private key", privateCertFile)
 		}
+		key = kf.Key
 	}
 
-	certs, err := x509.ParseCertificates(blocks)
-	if err != nil {
-		return certDetails, err
+	for _, cert := range f.Certificates {
+		cd := NewCertificateDetails(cert, currentTime)
+		cd.Source = publicCertFile
+		certDetails = append(certDetails, cd)
+	}
+	if key != nil {
+		certDetails[0].KeyMismatch = !KeyMatches(key, f.Certificates[0])
 	}
 
-	for _, cert := range certs {
-		daysUntilExpiration := int(cert.NotAfter.Sub(currentTime).Hours() / 24)
-		subjectName := cert.Subject.Names[len(cert.Subject.Names)-1].Value.(string)
-		issuerName := cert.Issuer.Names[len(cert.Issuer.Names)-1].Value.(string)
-		serialNumber := cert.SerialNumber.Text(16)
-		elapsed := time.Since(currentTime)
-
-		certDetails = append(certDetails, CertificateDetails{
-			DaysUntilExpiration: daysUntilExpiration,
-			SubjectName:         subjectName,
-			IssuerName:          issuerName,
-			SerialNumber:        strings.ToUpper(insertNth(serialNumber, 2)),
-			TimeTaken:           elapsed,
-			ExpirationDate:      cert.NotAfter.Format(time.UnixDate),
-		})
+	return certDetails, nil
+}
 
+// NewCertificateDetails returns the details of a certificate, as of now
+func NewCertificateDetails(cert *x509.Certificate, now time.Time) CertificateDetails {
+	return CertificateDetails{
+		DaysUntilExpiration: int(cert.NotAfter.Sub(now).Hours() / 24),
+		SubjectName:         displayName(cert.Subject),
+		IssuerName:          displayName(cert.Issuer),
+		SerialNumber:        strings.ToUpper(insertNth(cert.SerialNumber.Text(16), 2)),
+		ExpirationDate:      cert.NotAfter.Format(time.UnixDate),
+		NotAfter:            cert.NotAfter,
+		Thumbprint:          Thumbprint(cert),
+		DNSNames:            cert.DNSNames,
 	}
+}
 
-	return certDetails, nil
+// displayName returns the common name of a certificate subject or issuer, or the full name if it
+// has none
+func displayName(n pkix.Name) string {
+	if n.CommonName != "" {
+		return n.CommonName
+	}
+	return n.String()
 }
 
 // GetCertificateDetails gets a certificate and its details
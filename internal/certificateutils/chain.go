@@ -0,0 +1,314 @@
+package certificateutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severities of chain findings, named after the host service statuses they map to
+const (
+	SeverityWarning = "warning"
+	SeverityProblem = "problem"
+)
+
+// Finding is something wrong with a certificate chain
+type Finding struct {
+	Severity string
+	Message  string
+}
+
+// VerifyOptions control how a certificate chain is verified
+type VerifyOptions struct {
+	// ServerName is the name the leaf certificate must cover
+	ServerName string
+	// Roots are the trusted roots; nil means the system roots
+	Roots *x509.CertPool
+	// Now is the time to verify at; the zero value means time.Now()
+	Now time.Time
+	// WarningDays and ProblemDays are how close the earliest expiry in the chain may come
+	// before it is reported as a warning or a problem
+	WarningDays int
+	ProblemDays int
+}
+
+// ChainReport is the result of verifying a certificate chain
+type ChainReport struct {
+	Leaf     *x509.Certificate
+	Chain    []*x509.Certificate
+	Verified bool
+	// EarliestExpiry is the certificate in the chain that expires first
+	EarliestExpiry *x509.Certificate
+	Findings       []Finding
+}
+
+// Status returns the status for the report: problem or warning if any finding is, otherwise healthy
+func (r ChainReport) Status() string {
+	status := "healthy"
+	for _, f := range r.Findings {
+		if f.Severity == SeverityProblem {
+			return SeverityProblem
+		}
+		status = SeverityWarning
+	}
+	return status
+}
+
+// DaysUntilExpiry returns the number of days until the earliest expiry in the chain
+func (r ChainReport) DaysUntilExpiry(now time.Time) int {
+	if r.EarliestExpiry == nil {
+		return 0
+	}
+	return int(r.EarliestExpiry.NotAfter.Sub(now).Hours() / 24)
+}
+
+// FetchChain connects to address and returns the certificates the server presents. Protocol is
+// one of the Protocol constants; for anything other than direct TLS (the default when empty), the
+// connection is switched to TLS with the protocol's STARTTLS negotiation first. Certificates are
+// not verified while connecting, so that an invalid chain can still be inspected.
+func FetchChain(ctx context.Context, address, serverName, protocol string) ([]*x509.Certificate, error) {
+	if address == "" {
+		return nil, hostnameEmptyError
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("connection error: %v", err)
+	}
+	defer conn.Close()
+
+	// the STARTTLS negotiation doesn't take a context, so give up on the connection when it ends
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := startTLS(conn, protocol); err != nil {
+		return nil, fmt.Errorf("connection error: %v", err)
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, // verified by VerifyChain
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("connection error: %v", err)
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s presented no certificates", address)
+	}
+	return certs, nil
+}
+
+// VerifyChain checks the certificates presented by a server (leaf first): that they chain to a
+// trusted root, that the leaf covers the server name, that no certificate is expired or expiring
+// soon, and that no certificate uses a weak key or signature algorithm
+func VerifyChain(certs []*x509.Certificate, opts VerifyOptions) ChainReport {
+	var r ChainReport
+	if len(certs) == 0 {
+		r.Findings = append(r.Findings, Finding{SeverityProblem, "no certificates presented"})
+		return r
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	r.Leaf = certs[0]
+	r.Chain = certs
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	// verify the path without the name first, so that an untrusted chain and a name mismatch
+	// are reported separately
+	verifyOpts := x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	}
+	chains, err := r.Leaf.Verify(verifyOpts)
+	if isExpiryError(err) {
+		// expiry is reported below; check the chain is trusted as of just before it expired
+		verifyOpts.CurrentTime = earliestNotAfter(certs).Add(-time.Minute)
+		chains, err = r.Leaf.Verify(verifyOpts)
+	}
+	if err == nil {
+		r.Verified = true
+		r.Chain = chains[0]
+	} else if !isExpiryError(err) {
+		r.Findings = append(r.Findings, Finding{SeverityProblem, describeVerifyError(err)})
+	}
+
+	if opts.ServerName != "" {
+		if err := r.Leaf.VerifyHostname(opts.ServerName); err != nil {
+			r.Findings = append(r.Findings, Finding{SeverityProblem,
+				fmt.Sprintf("certificate does not cover %s (covers %s)", opts.ServerName, coveredNames(r.Leaf))})
+		}
+	}
+
+	r.Findings = append(r.Findings, expiryFindings(&r, now, opts)...)
+	r.Findings = append(r.Findings, strengthFindings(r.Chain)...)
+
+	return r
+}
+
+// isExpiryError reports whether a verification error is only about validity dates, which are
+// reported as expiry findings instead
+func isExpiryError(err error) bool {
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &invalid) && invalid.Reason == x509.Expired
+}
+
+func earliestNotAfter(certs []*x509.Certificate) time.Time {
+	t := certs[0].NotAfter
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(t) {
+			t = c.NotAfter
+		}
+	}
+	return t
+}
+
+// describeVerifyError explains why a chain did not verify
+func describeVerifyError(err error) string {
+	var unknown x509.UnknownAuthorityError
+	if errors.As(err, &unknown) {
+		if c := unknown.Cert; c != nil && c.Subject.String() == c.Issuer.String() {
+			return fmt.Sprintf("self-signed certificate %s is not trusted", name(c))
+		}
+		return fmt.Sprintf("chain is incomplete or not issued by a trusted authority: %s", err)
+	}
+	return fmt.Sprintf("chain does not verify: %s", err)
+}
+
+// expiryFindings reports certificates in the chain that have expired, are not yet valid, or
+// expire soon, and records the earliest expiry
+func expiryFindings(r *ChainReport, now time.Time, opts VerifyOptions) []Finding {
+	var findings []Finding
+
+	for _, c := range r.Chain {
+		if r.EarliestExpiry == nil || c.NotAfter.Before(r.EarliestExpiry.NotAfter) {
+			r.EarliestExpiry = c
+		}
+		if now.After(c.NotAfter) {
+			findings = append(findings, Finding{SeverityProblem,
+				fmt.Sprintf("%s expired on %s", name(c), c.NotAfter.Format("2006-01-02"))})
+		}
+		if now.Before(c.NotBefore) {
+			findings = append(findings, Finding{SeverityProblem,
+				fmt.Sprintf("%s is not valid until %s", name(c), c.NotBefore.Format("2006-01-02"))})
+		}
+	}
+
+	days := r.DaysUntilExpiry(now)
+	if days < 0 || now.After(r.EarliestExpiry.NotAfter) {
+		return findings
+	}
+
+	switch {
+	case days < opts.ProblemDays:
+		findings = append(findings, Finding{SeverityProblem,
+			fmt.Sprintf("%s expires in %d days", name(r.EarliestExpiry), days)})
+	case days < opts.WarningDays:
+		findings = append(findings, Finding{SeverityWarning,
+			fmt.Sprintf("%s expires in %d days", name(r.EarliestExpiry), days)})
+	}
+	return findings
+}
+
+// strengthFindings reports weak keys and signature algorithms. The signature on a self-signed
+// root is not checked, since trust in a root does not depend on it.
+func strengthFindings(chain []*x509.Certificate) []Finding {
+	var findings []Finding
+
+	for _, c := range chain {
+		switch k := c.PublicKey.(type) {
+		case *rsa.PublicKey:
+			if bits := k.N.BitLen(); bits < 2048 {
+				findings = append(findings, Finding{SeverityProblem, fmt.Sprintf("%s has a weak %d bit RSA key", name(c), bits)})
+			}
+		case *ecdsa.PublicKey:
+			if bits := k.Curve.Params().BitSize; bits < 256 {
+				findings = append(findings, Finding{SeverityProblem, fmt.Sprintf("%s has a weak %d bit ECDSA key", name(c), bits)})
+			}
+		}
+
+		if isSelfSigned(c) {
+			continue
+		}
+		switch c.SignatureAlgorithm {
+		case x509.MD2WithRSA, x509.MD5WithRSA:
+			findings = append(findings, Finding{SeverityProblem, fmt.Sprintf("%s is signed with %s", name(c), c.SignatureAlgorithm)})
+		case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+			findings = append(findings, Finding{SeverityWarning, fmt.Sprintf("%s is signed with %s", name(c), c.SignatureAlgorithm)})
+		}
+	}
+
+	return findings
+}
+
+func isSelfSigned(c *x509.Certificate) bool {
+	return c.Subject.String() == c.Issuer.String() && c.CheckSignatureFrom(c) == nil
+}
+
+// name returns a short name for a certificate, for messages
+func name(c *x509.Certificate) string {
+	if c.Subject.CommonName != "" {
+		return fmt.Sprintf("%q", c.Subject.CommonName)
+	}
+	if len(c.DNSNames) > 0 {
+		return fmt.Sprintf("%q", c.DNSNames[0])
+	}
+	return fmt.Sprintf("%q", c.Subject.String())
+}
+
+// coveredNames lists the names a certificate is valid for
+func coveredNames(c *x509.Certificate) string {
+	names := append([]string{}, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 {
+		return "no names"
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Thumbprint returns the SHA-256 fingerprint of a certificate as colon separated hex, the way
+// browsers show it
+func Thumbprint(c *x509.Certificate) string {
+	sum := sha256.Sum256(c.Raw)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	var b strings.Builder
+	for i := 0; i < len(hexSum); i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.WriteString(hexSum[i : i+2])
+	}
+	return b.String()
+}
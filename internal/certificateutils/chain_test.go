@@ -0,0 +1,194 @@
+package certificateutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// issue creates a certificate signed by parent, or a self-signed one when parent is nil
+func issue(t *testing.T, cn string, isCA bool, notAfter time.Time, key crypto.Signer, parent *testCert, sigAlg x509.SignatureAlgorithm) *testCert {
+	t.Helper()
+
+	if key == nil {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key = k
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             now.AddDate(-1, 0, 0),
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		SignatureAlgorithm:    sigAlg,
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{cn}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	signer, signerCert := key, tmpl
+	if parent != nil {
+		signer, signerCert = parent.key, parent.cert
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signerCert, key.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func TestVerifyChain(t *testing.T) {
+	far := now.AddDate(2, 0, 0)
+
+	root := issue(t, "Test Root", true, far, nil, nil, 0)
+	intermediate := issue(t, "Test Intermediate", true, far, nil, root, 0)
+	expiredIntermediate := issue(t, "Old Intermediate", true, now.AddDate(0, 0, -1), nil, root, 0)
+	leaf := issue(t, "www.example.com", false, far, nil, intermediate, 0)
+	leafOfExpired := issue(t, "www.example.com", false, far, nil, expiredIntermediate, 0)
+	soon := issue(t, "www.example.com", false, now.AddDate(0, 0, 20), nil, intermediate, 0)
+	verySoon := issue(t, "www.example.com", false, now.AddDate(0, 0, 3), nil, intermediate, 0)
+	selfSigned := issue(t, "www.example.com", false, far, nil, nil, 0)
+
+	rsaRoot, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaCA := issue(t, "RSA Root", true, far, rsaRoot, nil, 0)
+	weak := issue(t, "www.example.com", false, far, weakKey, rsaCA, 0)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	roots.AddCert(rsaCA.cert)
+
+	var tests = []struct {
+		name           string
+		chain          []*testCert
+		roots          *x509.CertPool
+		serverName     string
+		expectedStatus string
+		expectedText   string
+	}{
+		{"valid", []*testCert{leaf, intermediate}, roots, "www.example.com", "healthy", ""},
+		{"untrusted", []*testCert{leaf, intermediate}, x509.NewCertPool(), "www.example.com", "problem", "not issued by a trusted authority"},
+		{"incomplete", []*testCert{leaf}, roots, "www.example.com", "problem", "incomplete"},
+		{"self-signed", []*testCert{selfSigned}, roots, "www.example.com", "problem", "self-signed"},
+		{"hostname-mismatch", []*testCert{leaf, intermediate}, roots, "mail.example.com", "problem", "does not cover mail.example.com"},
+		{"expired-intermediate", []*testCert{leafOfExpired, expiredIntermediate}, roots, "www.example.com", "problem", `"Old Intermediate" expired`},
+		{"expiring-soon", []*testCert{soon, intermediate}, roots, "www.example.com", "warning", "expires in 20 days"},
+		{"expiring-very-soon", []*testCert{verySoon, intermediate}, roots, "www.example.com", "problem", "expires in 3 days"},
+		{"weak-key", []*testCert{weak}, roots, "www.example.com", "problem", "weak 1024 bit RSA key"},
+	}
+
+	for _, e := range tests {
+		var certs []*x509.Certificate
+		for _, c := range e.chain {
+			certs = append(certs, c.cert)
+		}
+
+		report := VerifyChain(certs, VerifyOptions{
+			ServerName:  e.serverName,
+			Roots:       e.roots,
+			Now:         now,
+			WarningDays: 30,
+			ProblemDays: 7,
+		})
+
+		if report.Status() != e.expectedStatus {
+			t.Errorf("%s: expected %s, but got %s (%v)", e.name, e.expectedStatus, report.Status(), report.Findings)
+		}
+
+		var messages []string
+		for _, f := range report.Findings {
+			messages = append(messages, f.Message)
+		}
+		if !strings.Contains(strings.Join(messages, "; "), e.expectedText) {
+			t.Errorf("%s: expected a finding containing %q, but got %v", e.name, e.expectedText, messages)
+		}
+	}
+}
+
+func TestVerifyChainEarliestExpiry(t *testing.T) {
+	root := issue(t, "Test Root", true, now.AddDate(5, 0, 0), nil, nil, 0)
+	intermediate := issue(t, "Test Intermediate", true, now.AddDate(0, 0, 100), nil, root, 0)
+	leaf := issue(t, "www.example.com", false, now.AddDate(1, 0, 0), nil, intermediate, 0)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	report := VerifyChain([]*x509.Certificate{leaf.cert, intermediate.cert}, VerifyOptions{
+		ServerName: "www.example.com",
+		Roots:      roots,
+		Now:        now,
+	})
+
+	if !report.Verified {
+		t.Errorf("expected chain to verify, but got %v", report.Findings)
+	}
+	if report.EarliestExpiry != intermediate.cert {
+		t.Errorf("expected earliest expiry to be the intermediate, but got %s", report.EarliestExpiry.Subject.CommonName)
+	}
+	if days := report.DaysUntilExpiry(now); days != 100 {
+		t.Errorf("expected 100 days until expiry, but got %d", days)
+	}
+}
+
+func TestVerifyChainSHA1(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := issue(t, "RSA Root", true, now.AddDate(5, 0, 0), key, nil, 0)
+	leaf := issue(t, "www.example.com", false, now.AddDate(1, 0, 0), nil, root, x509.SHA1WithRSA)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	report := VerifyChain([]*x509.Certificate{leaf.cert}, VerifyOptions{
+		ServerName: "www.example.com",
+		Roots:      roots,
+		Now:        now,
+	})
+
+	found := false
+	for _, f := range report.Findings {
+		if strings.Contains(f.Message, "signed with SHA1-RSA") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a SHA-1 signature finding, but got %v", report.Findings)
+	}
+}
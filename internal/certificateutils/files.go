@@ -0,0 +1,245 @@
+package certificateutils
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxPEMFileSize caps the size of a file read as PEM; certificate bundles are far smaller
+const maxPEMFileSize = 1 << 20
+
+// pemExtensions are the file extensions read when scanning a directory
+var pemExtensions = map[string]bool{
+	".pem":  true,
+	".crt":  true,
+	".cer":  true,
+	".cert": true,
+	".key":  true,
+}
+
+// PEMFile is what a PEM file holds: certificates, and the public half of a private key, if any
+type PEMFile struct {
+	Path         string
+	Certificates []*x509.Certificate
+	Key          crypto.PublicKey
+}
+
+// ReadPEMFile reads the certificates and private key in a PEM file. Blocks of other types, and
+// encrypted keys, are skipped.
+func ReadPEMFile(path string) (PEMFile, error) {
+	f := PEMFile{Path: path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return f, err
+	}
+	if info.Size() > maxPEMFileSize {
+		return f, fmt.Errorf("%s is too large to be a certificate file", path)
+	}
+	rest, err := os.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		switch {
+		case block.Type == "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return f, fmt.Errorf("%s: %v", path, err)
+			}
+			f.Certificates = append(f.Certificates, cert)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY") && f.Key == nil:
+			if key := parsePrivateKey(block.Bytes); key != nil {
+				f.Key = key.Public()
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// parsePrivateKey parses a PKCS #8, PKCS #1 or EC private key
+func parsePrivateKey(der []byte) crypto.Signer {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer
+		}
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key
+	}
+	return nil
+}
+
+// KeyMatches reports whether a public key, taken from a private key, belongs to a certificate
+func KeyMatches(key crypto.PublicKey, cert *x509.Certificate) bool {
+	k, ok := key.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(cert.PublicKey)
+}
+
+// FindPEMFiles expands a list of files, directories and glob patterns into the files to read.
+// Directories are not read recursively, and hidden entries (such as the ..data links in
+// Kubernetes secret volumes) are skipped.
+func FindPEMFiles(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no such file or directory", pattern)
+		}
+
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(m)
+				continue
+			}
+
+			entries, err := os.ReadDir(m)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				p := filepath.Join(m, e.Name())
+				if strings.HasPrefix(e.Name(), ".") || !pemExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+					continue
+				}
+				// follow links, as secret volumes are made of them
+				if info, err := os.Stat(p); err == nil && !info.IsDir() {
+					add(p)
+				}
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// FileScan is the result of scanning certificate files
+type FileScan struct {
+	Certificates []CertificateDetails
+	Findings     []Finding
+}
+
+// ScanPEMFiles reads the certificates in the files matching patterns, and checks private keys
+// against them. A key is paired with the certificate in the same file, or in the file with the
+// same name apart from its extension (server.key and server.crt, tls.key and tls.crt); a key with
+// no such pair must belong to one of the certificates found.
+func ScanPEMFiles(patterns []string, now time.Time) (FileScan, error) {
+	var scan FileScan
+
+	paths, err := FindPEMFiles(patterns)
+	if err != nil {
+		return scan, err
+	}
+
+	var files []PEMFile
+	byStem := make(map[string][]PEMFile)
+	for _, p := range paths {
+		f, err := ReadPEMFile(p)
+		if err != nil {
+			scan.Findings = append(scan.Findings, Finding{SeverityWarning, fmt.Sprintf("could not read %s: %v", p, err)})
+			continue
+		}
+		files = append(files, f)
+		stem := strings.TrimSuffix(p, filepath.Ext(p))
+		byStem[stem] = append(byStem[stem], f)
+	}
+
+	paired := make(map[string]bool)
+	for _, f := range files {
+		if len(f.Certificates) == 0 {
+			continue
+		}
+
+		keyPath := ""
+		if f.Key == nil {
+			keyPath = keyWithStem(byStem[strings.TrimSuffix(f.Path, filepath.Ext(f.Path))])
+			paired[keyPath] = true
+		}
+
+		certs, err := ReadCertificateDetailsFromFile(f.Path, keyPath)
+		if err != nil {
+			scan.Findings = append(scan.Findings, Finding{SeverityWarning, fmt.Sprintf("could not read %s: %v", f.Path, err)})
+			continue
+		}
+		for i := range certs {
+			certs[i].DaysUntilExpiration = int(certs[i].NotAfter.Sub(now).Hours() / 24)
+		}
+		if certs[0].KeyMismatch {
+			keyFile := f.Path
+			if keyPath != "" {
+				keyFile = keyPath
+			}
+			scan.Findings = append(scan.Findings, Finding{SeverityWarning,
+				fmt.Sprintf("private key in %s does not match the certificate in %s", keyFile, f.Path)})
+		}
+		scan.Certificates = append(scan.Certificates, certs...)
+	}
+
+	for _, f := range files {
+		if f.Key == nil || len(f.Certificates) > 0 || paired[f.Path] {
+			continue
+		}
+		if !anyKeyMatch(f.Key, files) {
+			scan.Findings = append(scan.Findings, Finding{SeverityWarning,
+				fmt.Sprintf("private key in %s does not match any certificate", f.Path)})
+		}
+	}
+
+	return scan, nil
+}
+
+// keyWithStem returns the path of the key file among files sharing a certificate file's stem
+func keyWithStem(sameStem []PEMFile) string {
+	for _, f := range sameStem {
+		if f.Key != nil && len(f.Certificates) == 0 {
+			return f.Path
+		}
+	}
+	return ""
+}
+
+func anyKeyMatch(key crypto.PublicKey, files []PEMFile) bool {
+	for _, f := range files {
+		for _, c := range f.Certificates {
+			if KeyMatches(key, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
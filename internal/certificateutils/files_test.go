@@ -0,0 +1,107 @@
+package certificateutils
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePEM writes certificates and an optional private key to a PEM file in dir
+func writePEM(t *testing.T, dir, name string, key crypto.Signer, certs ...*testCert) {
+	t.Helper()
+
+	var b []byte
+	for _, c := range certs {
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})...)
+	}
+	if key != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})...)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanPEMFiles(t *testing.T) {
+	far := now.AddDate(1, 0, 0)
+	root := issue(t, "Test Root", true, far, nil, nil, 0)
+	web := issue(t, "www.example.com", false, far, nil, root, 0)
+	mail := issue(t, "mail.example.com", false, far, nil, root, 0)
+	other := issue(t, "other.example.com", false, far, nil, root, 0)
+
+	var tests = []struct {
+		name          string
+		files         func(dir string)
+		expectedCerts int
+		expectedText  string
+	}{
+		{"pair", func(dir string) {
+			writePEM(t, dir, "tls.crt", nil, web, root)
+			writePEM(t, dir, "tls.key", web.key)
+		}, 2, ""},
+		{"combined", func(dir string) {
+			writePEM(t, dir, "server.pem", web.key, web)
+		}, 1, ""},
+		{"pair-mismatch", func(dir string) {
+			writePEM(t, dir, "server.crt", nil, web)
+			writePEM(t, dir, "server.key", mail.key)
+		}, 1, "does not match the certificate in"},
+		{"combined-mismatch", func(dir string) {
+			writePEM(t, dir, "server.pem", other.key, web)
+		}, 1, "does not match the certificate in"},
+		{"unpaired-key-matches", func(dir string) {
+			writePEM(t, dir, "cert.pem", nil, mail)
+			writePEM(t, dir, "privkey.pem", mail.key)
+		}, 1, ""},
+		{"unpaired-key-no-match", func(dir string) {
+			writePEM(t, dir, "cert.pem", nil, mail)
+			writePEM(t, dir, "privkey.pem", other.key)
+		}, 1, "does not match any certificate"},
+		{"hidden-and-other-files", func(dir string) {
+			writePEM(t, dir, "web.crt", nil, web)
+			writePEM(t, dir, ".old.crt", nil, other)
+			writePEM(t, dir, "notes.txt", nil, other)
+		}, 1, ""},
+	}
+
+	for _, e := range tests {
+		dir := t.TempDir()
+		e.files(dir)
+
+		scan, err := ScanPEMFiles([]string{dir}, now)
+		if err != nil {
+			t.Errorf("%s: expected no error, but got %v", e.name, err)
+			continue
+		}
+		if len(scan.Certificates) != e.expectedCerts {
+			t.Errorf("%s: expected %d certificates, but got %d", e.name, e.expectedCerts, len(scan.Certificates))
+		}
+
+		var messages []string
+		for _, f := range scan.Findings {
+			messages = append(messages, f.Message)
+		}
+		joined := strings.Join(messages, "; ")
+		if e.expectedText == "" && joined != "" {
+			t.Errorf("%s: expected no findings, but got %v", e.name, messages)
+		}
+		if !strings.Contains(joined, e.expectedText) {
+			t.Errorf("%s: expected a finding containing %q, but got %v", e.name, e.expectedText, messages)
+		}
+	}
+}
+
+func TestFindPEMFilesMissing(t *testing.T) {
+	_, err := FindPEMFiles([]string{filepath.Join(t.TempDir(), "*.pem")})
+	if err == nil {
+		t.Error("expected an error for a pattern matching nothing, but got none")
+	}
+}
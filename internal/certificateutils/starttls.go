@@ -0,0 +1,250 @@
+package certificateutils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Protocols that negotiate TLS on a plain connection before the handshake. ProtocolTLS is a
+// direct TLS connection, as for HTTPS.
+const (
+	ProtocolTLS      = "tls"
+	ProtocolSMTP     = "smtp"
+	ProtocolIMAP     = "imap"
+	ProtocolPOP3     = "pop3"
+	ProtocolFTP      = "ftp"
+	ProtocolPostgres = "postgres"
+)
+
+// maxLineLength caps a line read from a server before STARTTLS, so a misbehaving server can't
+// make us buffer without limit
+const maxLineLength = 4096
+
+// starters holds the STARTTLS negotiation for each protocol, and its usual port
+var starters = map[string]struct {
+	port  int
+	start func(conn net.Conn) error
+}{
+	ProtocolTLS:      {443, nil},
+	ProtocolSMTP:     {25, startSMTP},
+	ProtocolIMAP:     {143, startIMAP},
+	ProtocolPOP3:     {110, startPOP3},
+	ProtocolFTP:      {21, startFTP},
+	ProtocolPostgres: {5432, startPostgres},
+}
+
+// Protocols returns the names of the supported protocols
+func Protocols() []string {
+	var names []string
+	for name := range starters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultPort returns the usual port for a protocol, or 0 if the protocol isn't supported. An
+// empty protocol means direct TLS.
+func DefaultPort(protocol string) int {
+	if protocol == "" {
+		protocol = ProtocolTLS
+	}
+	return starters[protocol].port
+}
+
+// startTLS asks the server on conn to switch to TLS, using the given protocol
+func startTLS(conn net.Conn, protocol string) error {
+	if protocol == "" {
+		protocol = ProtocolTLS
+	}
+	s, ok := starters[protocol]
+	if !ok {
+		return fmt.Errorf("unsupported protocol %q", protocol)
+	}
+	if s.start == nil {
+		return nil
+	}
+	if err := s.start(conn); err != nil {
+		return fmt.Errorf("%s starttls: %v", protocol, err)
+	}
+	return nil
+}
+
+// startSMTP sends EHLO then STARTTLS (RFC 3207)
+func startSMTP(conn net.Conn) error {
+	r := bufio.NewReaderSize(conn, maxLineLength)
+
+	if _, err := readReply(r, "220"); err != nil {
+		return err
+	}
+	if err := send(conn, "EHLO spectre"); err != nil {
+		return err
+	}
+	lines, err := readReply(r, "250")
+	if err != nil {
+		return err
+	}
+	if !hasCapability(lines, "STARTTLS") {
+		return fmt.Errorf("server does not offer STARTTLS")
+	}
+	if err := send(conn, "STARTTLS"); err != nil {
+		return err
+	}
+	_, err = readReply(r, "220")
+	return err
+}
+
+// startFTP sends AUTH TLS (RFC 4217)
+func startFTP(conn net.Conn) error {
+	r := bufio.NewReaderSize(conn, maxLineLength)
+
+	if _, err := readReply(r, "220"); err != nil {
+		return err
+	}
+	if err := send(conn, "AUTH TLS"); err != nil {
+		return err
+	}
+	_, err := readReply(r, "234")
+	return err
+}
+
+// startIMAP sends STARTTLS as a tagged command (RFC 3501)
+func startIMAP(conn net.Conn) error {
+	r := bufio.NewReaderSize(conn, maxLineLength)
+
+	greeting, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("unexpected greeting %q", greeting)
+	}
+	if err := send(conn, "a1 STARTTLS"); err != nil {
+		return err
+	}
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		// untagged responses may come before the tagged one
+		if strings.HasPrefix(line, "* ") {
+			continue
+		}
+		if strings.HasPrefix(line, "a1 OK") {
+			return nil
+		}
+		return fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+// startPOP3 sends STLS (RFC 2595)
+func startPOP3(conn net.Conn) error {
+	r := bufio.NewReaderSize(conn, maxLineLength)
+
+	for _, cmd := range []string{"", "STLS"} {
+		if cmd != "" {
+			if err := send(conn, cmd); err != nil {
+				return err
+			}
+		}
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "+OK") {
+			return fmt.Errorf("unexpected reply %q", line)
+		}
+	}
+	return nil
+}
+
+// postgresSSLRequest is the request code a PostgreSQL client sends to ask for TLS
+const postgresSSLRequest = 80877103
+
+// startPostgres sends an SSLRequest; the server answers with a single byte, S to go ahead
+func startPostgres(conn net.Conn) error {
+	req := make([]byte, 8)
+	binary.BigEndian.PutUint32(req[0:4], 8)
+	binary.BigEndian.PutUint32(req[4:8], postgresSSLRequest)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	switch resp[0] {
+	case 'S':
+		return nil
+	case 'N':
+		return fmt.Errorf("server does not accept SSL connections")
+	default:
+		return fmt.Errorf("unexpected reply %q", resp[0])
+	}
+}
+
+func send(conn net.Conn, cmd string) error {
+	_, err := io.WriteString(conn, cmd+"\r\n")
+	return err
+}
+
+// readLine reads a line without its line ending
+func readLine(r *bufio.Reader) (string, error) {
+	line, isPrefix, err := r.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	if isPrefix {
+		return "", fmt.Errorf("line longer than %d bytes", maxLineLength)
+	}
+	return string(line), nil
+}
+
+// readReply reads an SMTP or FTP reply, which may span several lines ("250-..." up to "250 ..."),
+// and checks its code
+func readReply(r *bufio.Reader, code string) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, code) {
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+
+	lines := []string{strings.TrimSpace(line[len(code):])}
+	if !strings.HasPrefix(line, code+"-") {
+		return lines, nil
+	}
+
+	// FTP allows continuation lines without the code, so read on until the closing "code " line
+	for {
+		line, err = readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		rest, coded := strings.CutPrefix(line, code)
+		lines = append(lines, strings.TrimSpace(rest))
+		if coded && (rest == "" || rest[0] == ' ') {
+			return lines, nil
+		}
+	}
+}
+
+// hasCapability reports whether an EHLO reply lists an extension
+func hasCapability(lines []string, ext string) bool {
+	for _, l := range lines {
+		l = strings.TrimPrefix(l, "-")
+		if fields := strings.Fields(l); len(fields) > 0 && strings.EqualFold(fields[0], ext) {
+			return true
+		}
+	}
+	return false
+}
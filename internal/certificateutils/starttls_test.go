@@ -0,0 +1,154 @@
+package certificateutils
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// step is one exchange in a scripted server: the line it expects from the client (none for the
+// greeting), then what it replies
+type step struct {
+	expect string
+	reply  string
+}
+
+// scriptedServer plays a script on conn, and returns whether the client followed it
+func scriptedServer(conn net.Conn, script []step) <-chan bool {
+	ok := make(chan bool, 1)
+	go func() {
+		r := bufio.NewReader(conn)
+		for _, s := range script {
+			if s.expect != "" {
+				line, err := r.ReadString('\n')
+				if err != nil || strings.TrimRight(line, "\r\n") != s.expect {
+					ok <- false
+					return
+				}
+			}
+			if _, err := io.WriteString(conn, s.reply); err != nil {
+				ok <- false
+				return
+			}
+		}
+		ok <- true
+	}()
+	return ok
+}
+
+func TestStartTLS(t *testing.T) {
+	var tests = []struct {
+		name        string
+		protocol    string
+		script      []step
+		expectError bool
+	}{
+		{"tls", "", nil, false},
+		{"smtp", ProtocolSMTP, []step{
+			{"", "220 mail.example.com ESMTP\r\n"},
+			{"EHLO spectre", "250-mail.example.com\r\n250-PIPELINING\r\n250-STARTTLS\r\n250 8BITMIME\r\n"},
+			{"STARTTLS", "220 Ready to start TLS\r\n"},
+		}, false},
+		{"smtp-no-starttls", ProtocolSMTP, []step{
+			{"", "220 mail.example.com ESMTP\r\n"},
+			{"EHLO spectre", "250-mail.example.com\r\n250 8BITMIME\r\n"},
+		}, true},
+		{"imap", ProtocolIMAP, []step{
+			{"", "* OK IMAP4rev1 ready\r\n"},
+			{"a1 STARTTLS", "* CAPABILITY IMAP4rev1\r\na1 OK Begin TLS negotiation now\r\n"},
+		}, false},
+		{"imap-refused", ProtocolIMAP, []step{
+			{"", "* OK IMAP4rev1 ready\r\n"},
+			{"a1 STARTTLS", "a1 BAD STARTTLS not available\r\n"},
+		}, true},
+		{"pop3", ProtocolPOP3, []step{
+			{"", "+OK POP3 ready\r\n"},
+			{"STLS", "+OK Begin TLS negotiation\r\n"},
+		}, false},
+		{"ftp", ProtocolFTP, []step{
+			{"", "220-Welcome\r\nsecond line of the banner\r\n220 Ready\r\n"},
+			{"AUTH TLS", "234 AUTH TLS successful\r\n"},
+		}, false},
+		{"postgres", ProtocolPostgres, []step{{"", "S"}}, false},
+		{"postgres-no-ssl", ProtocolPostgres, []step{{"", "N"}}, true},
+		{"unsupported", "gopher", nil, true},
+	}
+
+	for _, e := range tests {
+		client, server := net.Pipe()
+		_ = client.SetDeadline(time.Now().Add(2 * time.Second))
+		_ = server.SetDeadline(time.Now().Add(2 * time.Second))
+
+		if e.protocol == ProtocolPostgres {
+			// the SSLRequest is binary, so read it here rather than as a line
+			go func() {
+				req := make([]byte, 8)
+				if _, err := io.ReadFull(server, req); err == nil {
+					_, _ = server.Write([]byte(e.script[0].reply))
+				}
+			}()
+		} else {
+			scriptedServer(server, e.script)
+		}
+
+		err := startTLS(client, e.protocol)
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestFetchChainSTARTTLS(t *testing.T) {
+	root := issue(t, "Test Root", true, now.AddDate(5, 0, 0), nil, nil, 0)
+	leaf := issue(t, "mail.example.com", false, now.AddDate(1, 0, 0), nil, root, 0)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		ok := <-scriptedServer(conn, []step{
+			{"", "220 mail.example.com ESMTP\r\n"},
+			{"EHLO spectre", "250-mail.example.com\r\n250 STARTTLS\r\n"},
+			{"STARTTLS", "220 Ready to start TLS\r\n"},
+		})
+		if !ok {
+			return
+		}
+
+		tlsConn := tls.Server(conn, &tls.Config{
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{leaf.cert.Raw, root.cert.Raw},
+				PrivateKey:  leaf.key,
+			}},
+		})
+		_ = tlsConn.Handshake()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	certs, err := FetchChain(ctx, l.Addr().String(), "mail.example.com", ProtocolSMTP)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if len(certs) != 2 || !certs[0].Equal(leaf.cert) {
+		t.Errorf("expected the leaf and root, but got %d certificates", len(certs))
+	}
+}
@@ -0,0 +1,148 @@
+// Package certfilecheck implements the certificate files check: it reads PEM certificates from
+// files and directories on the machine running the check (the spectre server, or a remote agent),
+// reports on the one that expires soonest, and warns when a private key does not belong to its
+// certificate. Every certificate found is listed in the certificate inventory.
+package certfilecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wtran29/spectre/internal/certificateutils"
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/checkers/sslcheck"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("Certificate Files", &Checker{})
+}
+
+// Config is the per host service configuration for the certificate files check. Paths are
+// absolute files, directories (read one level deep) or glob patterns, such as
+// /etc/ssl/certs/*.pem or the mount point of a Kubernetes TLS secret. WarningDays and ProblemDays
+// override the default certificate expiry thresholds.
+type Config struct {
+	Paths       []string `json:"paths"`
+	WarningDays int      `json:"warning_days"`
+	ProblemDays int      `json:"problem_days"`
+}
+
+// Checker reads certificate files and reports on their expiry and keys
+type Checker struct{}
+
+// Check performs the certificate files check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	d := sslcheck.DefaultThresholds()
+	cfg := Config{WarningDays: d.WarningDays, ProblemDays: d.ProblemDays}
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+	if len(cfg.Paths) == 0 {
+		return checkers.Result{Status: "problem", Message: "no certificate paths configured"}
+	}
+
+	scan, err := certificateutils.ScanPEMFiles(cfg.Paths, time.Now())
+	if err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+	if ctx.Err() != nil {
+		return checkers.Result{Status: "problem", Message: checkers.ContextError(ctx, hs).Error()}
+	}
+
+	return result(scan, cfg)
+}
+
+// result turns a scan into the outcome of the check
+func result(scan certificateutils.FileScan, cfg Config) checkers.Result {
+	res := checkers.Result{Certificates: scan.Certificates}
+	if res.Certificates == nil {
+		// an empty list, rather than nil, clears the inventory
+		res.Certificates = []certificateutils.CertificateDetails{}
+	}
+
+	var messages []string
+	status := "healthy"
+	for _, f := range scan.Findings {
+		messages = append(messages, f.Message)
+		status = worse(status, f.Severity)
+	}
+
+	if len(scan.Certificates) == 0 {
+		res.Status = "problem"
+		res.Message = strings.Join(append([]string{"no certificates found"}, messages...), "; ")
+		return res
+	}
+
+	soonest := scan.Certificates[0]
+	for _, cd := range scan.Certificates[1:] {
+		if cd.NotAfter.Before(soonest.NotAfter) {
+			soonest = cd
+		}
+	}
+
+	days := soonest.DaysUntilExpiration
+	summary := fmt.Sprintf("%d certificates; %q in %s", len(scan.Certificates), soonest.SubjectName, filepath.Base(soonest.Source))
+	switch {
+	case days < 0:
+		status = "problem"
+		summary = fmt.Sprintf("%s expired on %s", summary, soonest.NotAfter.Format("2006-01-02"))
+	case days < cfg.ProblemDays:
+		status = "problem"
+		summary = fmt.Sprintf("%s expires in %d days", summary, days)
+	case days < cfg.WarningDays:
+		status = worse(status, "warning")
+		summary = fmt.Sprintf("%s expires in %d days", summary, days)
+	default:
+		summary = fmt.Sprintf("%s expires first, in %d days", summary, days)
+	}
+
+	res.Status = status
+	res.Message = strings.Join(append([]string{summary}, messages...), "; ")
+	res.Metrics = map[string]float64{
+		"days_until_expiry": float64(days),
+		"certificates":      float64(len(scan.Certificates)),
+	}
+	return res
+}
+
+// worse returns the more severe of two statuses
+func worse(a, b string) string {
+	if a == "problem" || b == "problem" {
+		return "problem"
+	}
+	if a == "warning" || b == "warning" {
+		return "warning"
+	}
+	return a
+}
+
+// Validate checks that the configured paths are absolute patterns and the thresholds make sense
+func (c *Checker) Validate(hs models.HostService) error {
+	var cfg Config
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return err
+	}
+	if len(cfg.Paths) == 0 {
+		return errors.New("paths must list at least one file or directory")
+	}
+	for _, p := range cfg.Paths {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("%q is not an absolute path", p)
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+	}
+	if cfg.WarningDays < 0 || cfg.ProblemDays < 0 {
+		return errors.New("warning_days and problem_days must not be negative")
+	}
+	if cfg.WarningDays > 0 && cfg.ProblemDays > cfg.WarningDays {
+		return errors.New("problem_days must not be more than warning_days")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package certfilecheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/certificateutils"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func TestResult(t *testing.T) {
+	now := time.Now()
+	cert := func(subject string, days int) certificateutils.CertificateDetails {
+		return certificateutils.CertificateDetails{
+			SubjectName:         subject,
+			Source:              "/etc/ssl/" + subject + ".pem",
+			NotAfter:            now.AddDate(0, 0, days),
+			DaysUntilExpiration: days,
+		}
+	}
+	mismatch := certificateutils.Finding{Severity: certificateutils.SeverityWarning, Message: "private key in /etc/ssl/a.key does not match the certificate in /etc/ssl/a.pem"}
+	cfg := Config{WarningDays: 30, ProblemDays: 7}
+
+	var tests = []struct {
+		name           string
+		scan           certificateutils.FileScan
+		expectedStatus string
+		expectedText   string
+	}{
+		{"healthy", certificateutils.FileScan{Certificates: []certificateutils.CertificateDetails{cert("a", 200), cert("b", 90)}}, "healthy", `"b" in b.pem expires first, in 90 days`},
+		{"warning", certificateutils.FileScan{Certificates: []certificateutils.CertificateDetails{cert("a", 200), cert("b", 20)}}, "warning", "expires in 20 days"},
+		{"problem", certificateutils.FileScan{Certificates: []certificateutils.CertificateDetails{cert("a", 3)}}, "problem", "expires in 3 days"},
+		{"expired", certificateutils.FileScan{Certificates: []certificateutils.CertificateDetails{cert("a", -2)}}, "problem", "expired on"},
+		{"key-mismatch", certificateutils.FileScan{Certificates: []certificateutils.CertificateDetails{cert("a", 200)}, Findings: []certificateutils.Finding{mismatch}}, "warning", "does not match"},
+		{"none", certificateutils.FileScan{}, "problem", "no certificates found"},
+	}
+
+	for _, e := range tests {
+		res := result(e.scan, cfg)
+		if res.Status != e.expectedStatus {
+			t.Errorf("%s: expected %s, but got %s (%s)", e.name, e.expectedStatus, res.Status, res.Message)
+		}
+		if !strings.Contains(res.Message, e.expectedText) {
+			t.Errorf("%s: expected message containing %q, but got %q", e.name, e.expectedText, res.Message)
+		}
+		if res.Certificates == nil {
+			t.Errorf("%s: expected the certificates to be listed for the inventory, but got nil", e.name)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{"valid", `{"paths": ["/etc/ssl/certs/*.pem", "/var/run/secrets/tls"]}`, false},
+		{"no-paths", `{}`, true},
+		{"relative", `{"paths": ["certs/server.pem"]}`, true},
+		{"bad-pattern", `{"paths": ["/etc/ssl/[.pem"]}`, true},
+		{"thresholds", `{"paths": ["/etc/ssl"], "warning_days": 5, "problem_days": 10}`, true},
+	}
+
+	c := &Checker{}
+	for _, e := range tests {
+		err := c.Validate(models.HostService{Config: e.config})
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package checkers holds the registry of service checks that spectre can run against a host. Each
+// check type lives in its own package and registers itself here, keyed by the service_name of its
+// row in the services table.
+package checkers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtran29/spectre/internal/certificateutils"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// Checker is implemented by every service type that can be monitored
+type Checker interface {
+	// Check runs the check for a host service and reports the outcome
+	Check(ctx context.Context, h models.Host, hs models.HostService) Result
+}
+
+// Validator is implemented by checkers that can verify a host service's configuration before it
+// is saved
+type Validator interface {
+	Validate(hs models.HostService) error
+}
+
+// Result holds the outcome of a single check. Metrics holds any measurements taken by the check,
+// such as response times in milliseconds, keyed by name.
+//
+// Checks that compare one run with the next return what they need to remember in State; it is
+// saved with the host service and passed back as HostService.CheckState on the next run. Notices
+// are recorded as events whether or not the status changed.
+//
+// Checks that find certificates list them in Certificates, which replaces what the certificate
+// inventory holds for the host service; nil leaves the inventory alone.
+type Result struct {
+	Status       string
+	Message      string
+	Metrics      map[string]float64
+	State        string
+	Notices      []Notice
+	Certificates []certificateutils.CertificateDetails
+}
+
+// Notice is something a check noticed that is worth an event of its own, such as a certificate
+// being replaced
+type Notice struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// DefaultTimeout is how long a check may run when its host service sets no timeout_seconds
+const DefaultTimeout = 10 * time.Second
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Checker)
+	names    = make(map[string]string)
+)
+
+// Register makes a checker available under the given service name. It panics if the name is
+// registered twice or the checker is nil, in the same way database/sql handles drivers.
+func Register(serviceName string, c Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c == nil {
+		panic("checkers: Register checker is nil")
+	}
+
+	key := normalize(serviceName)
+	if _, dup := registry[key]; dup {
+		panic("checkers: Register called twice for " + serviceName)
+	}
+	registry[key] = c
+	names[key] = serviceName
+}
+
+// Get returns the checker registered for a service name
+func Get(serviceName string) (Checker, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c, ok := registry[normalize(serviceName)]
+	return c, ok
+}
+
+// Run checks a host service with the checker registered for its service, limited to the host
+// service's timeout. Both the server and remote agents run checks this way.
+func Run(ctx context.Context, h models.Host, hs models.HostService) Result {
+	c, ok := Get(hs.Service.ServiceName)
+	if !ok {
+		return Result{
+			Status:  "problem",
+			Message: fmt.Sprintf("no checker registered for service %s", hs.Service.ServiceName),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout(hs))
+	defer cancel()
+	return c.Check(ctx, h, hs)
+}
+
+// Names returns the sorted list of registered service names
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var list []string
+	for _, n := range names {
+		list = append(list, n)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Validate verifies the configuration of a host service, using the checker registered for its
+// service when that checker implements Validator
+func Validate(hs models.HostService) error {
+	c, ok := Get(hs.Service.ServiceName)
+	if !ok {
+		return nil
+	}
+
+	if v, ok := c.(Validator); ok {
+		return v.Validate(hs)
+	}
+
+	var cfg map[string]interface{}
+	return DecodeConfig(hs, &cfg)
+}
+
+// DecodeConfig unmarshals the configuration stored with a host service into cfg. An empty
+// configuration leaves cfg untouched, so callers should set their defaults before calling.
+func DecodeConfig(hs models.HostService, cfg interface{}) error {
+	if strings.TrimSpace(hs.Config) == "" {
+		return nil
+	}
+
+	err := json.Unmarshal([]byte(hs.Config), cfg)
+	if err != nil {
+		return fmt.Errorf("invalid configuration for %s: %w", hs.Service.ServiceName, err)
+	}
+	return nil
+}
+
+// normalize makes registry lookups insensitive to case and surrounding space
+func normalize(serviceName string) string {
+	return strings.ToLower(strings.TrimSpace(serviceName))
+}
+
+// Timeout returns how long a check for the host service may run, from the timeout_seconds key of
+// its configuration
+func Timeout(hs models.HostService) time.Duration {
+	var cfg struct {
+		TimeoutSeconds int `json:"timeout_seconds"`
+	}
+	if err := DecodeConfig(hs, &cfg); err != nil || cfg.TimeoutSeconds <= 0 {
+		return DefaultTimeout
+	}
+	return time.Duration(cfg.TimeoutSeconds) * time.Second
+}
+
+// Retries returns how many consecutive checks of the host service must report a new status before
+// it replaces the current one, from the retries key of its configuration, and how soon to check
+// again while a change is being confirmed, from retry_seconds. A zero interval means the normal
+// schedule.
+func Retries(hs models.HostService) (int, time.Duration) {
+	var cfg struct {
+		Retries      int `json:"retries"`
+		RetrySeconds int `json:"retry_seconds"`
+	}
+	if err := DecodeConfig(hs, &cfg); err != nil {
+		return 1, 0
+	}
+	if cfg.Retries < 1 {
+		cfg.Retries = 1
+	}
+	if cfg.RetrySeconds < 0 {
+		cfg.RetrySeconds = 0
+	}
+	return cfg.Retries, time.Duration(cfg.RetrySeconds) * time.Second
+}
+
+// ContextError describes why a check's context ended, so that a timeout reads differently from
+// a connection error
+func ContextError(ctx context.Context, hs models.HostService) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %s", Timeout(hs))
+	case errors.Is(ctx.Err(), context.Canceled):
+		return errors.New("check cancelled")
+	}
+	return ctx.Err()
+}
+
+// HostName returns the bare host name from a host's URL, without scheme, port or path
+func HostName(h models.Host) string {
+	name := strings.TrimSpace(h.URL)
+	name = strings.TrimPrefix(name, "https://")
+	name = strings.TrimPrefix(name, "http://")
+	if i := strings.IndexAny(name, "/?#"); i >= 0 {
+		name = name[:i]
+	}
+	if host, _, err := net.SplitHostPort(name); err == nil {
+		name = host
+	}
+	return name
+}
+
+// Address returns the address used to reach a host: its IPv4 address if set, then its IPv6
+// address, and otherwise the host name from its URL
+func Address(h models.Host) string {
+	if h.IP != "" {
+		return h.IP
+	}
+	if h.IPV6 != "" {
+		return h.IPV6
+	}
+	return HostName(h)
+}
@@ -0,0 +1,74 @@
+package checkers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+type dummyChecker struct{}
+
+func (d *dummyChecker) Check(ctx context.Context, h models.Host, hs models.HostService) Result {
+	return Result{Status: "healthy", Message: "ok"}
+}
+
+func TestRegister(t *testing.T) {
+	Register("Dummy Service", &dummyChecker{})
+
+	if _, ok := Get("dummy service"); !ok {
+		t.Error("expected to find checker registered as Dummy Service")
+	}
+
+	if _, ok := Get("not registered"); ok {
+		t.Error("found a checker that was never registered")
+	}
+
+	found := false
+	for _, n := range Names() {
+		if n == "Dummy Service" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Dummy Service missing from Names()")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic when registering the same name twice")
+		}
+	}()
+	Register("DUMMY SERVICE", &dummyChecker{})
+}
+
+var decodeTests = []struct {
+	name        string
+	config      string
+	expectedErr bool
+	expectedVal int
+}{
+	{"empty", "", false, 5},
+	{"empty-object", "{}", false, 5},
+	{"value", `{"value": 10}`, false, 10},
+	{"invalid", `{"value": "ten"`, true, 5},
+}
+
+func TestDecodeConfig(t *testing.T) {
+	for _, e := range decodeTests {
+		cfg := struct {
+			Value int `json:"value"`
+		}{Value: 5}
+
+		err := DecodeConfig(models.HostService{Config: e.config}, &cfg)
+		if e.expectedErr && err == nil {
+			t.Errorf("%s: expected error but got none", e.name)
+		}
+		if !e.expectedErr && err != nil {
+			t.Errorf("%s: unexpected error: %s", e.name, err)
+		}
+		if !e.expectedErr && cfg.Value != e.expectedVal {
+			t.Errorf("%s: expected %d, but got %d", e.name, e.expectedVal, cfg.Value)
+		}
+	}
+}
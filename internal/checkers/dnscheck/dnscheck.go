@@ -0,0 +1,208 @@
+// Package dnscheck implements the DNS resolution check
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("DNS", &Checker{})
+}
+
+// Config is the per host service configuration for a DNS check. Every value in Expected must be
+// among the answers. MatchHostIP, on unless the config turns it off, also requires the host's IP
+// (for A) or IPV6 (for AAAA) to be among them.
+type Config struct {
+	RecordType  string   `json:"record_type"`
+	Nameserver  string   `json:"nameserver"`
+	Expected    []string `json:"expected"`
+	MatchHostIP bool     `json:"match_host_ip"`
+	WarningMS   int      `json:"warning_ms"`
+}
+
+// Checker resolves a record for the host's canonical name and compares the answers
+type Checker struct{}
+
+// Check performs the DNS check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	cfg := Config{
+		RecordType:  "A",
+		MatchHostIP: true,
+		WarningMS:   500,
+	}
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+	cfg.RecordType = strings.ToUpper(cfg.RecordType)
+
+	name := h.CanonicalName
+	if name == "" {
+		name = checkers.HostName(h)
+	}
+
+	start := time.Now()
+	answers, err := lookup(ctx, resolver(cfg.Nameserver), cfg.RecordType, name)
+	latency := time.Since(start)
+	metrics := map[string]float64{"lookup_ms": float64(latency.Microseconds()) / 1000}
+	label := fmt.Sprintf("%s %s", cfg.RecordType, name)
+
+	if err != nil {
+		var dnsErr *net.DNSError
+		switch {
+		case ctx.Err() != nil:
+			err = checkers.ContextError(ctx, hs)
+		case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+			err = errors.New("NXDOMAIN")
+		}
+		return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - %s", label, err), Metrics: metrics}
+	}
+
+	expected := cfg.Expected
+	if cfg.RecordType != "TXT" {
+		expected = normalize(expected)
+	}
+	if cfg.MatchHostIP {
+		switch {
+		case cfg.RecordType == "A" && h.IP != "":
+			expected = append(expected, h.IP)
+		case cfg.RecordType == "AAAA" && h.IPV6 != "":
+			expected = append(expected, normalize([]string{h.IPV6})...)
+		}
+	}
+
+	if missing := missingValues(expected, answers); len(missing) > 0 {
+		return checkers.Result{
+			Status:  "problem",
+			Message: fmt.Sprintf("%s - answers %s do not include %s", label, strings.Join(answers, ", "), strings.Join(missing, ", ")),
+			Metrics: metrics,
+		}
+	}
+
+	msg := fmt.Sprintf("%s - %s in %dms", label, strings.Join(answers, ", "), latency.Milliseconds())
+	if cfg.WarningMS > 0 && latency > time.Duration(cfg.WarningMS)*time.Millisecond {
+		return checkers.Result{Status: "warning", Message: fmt.Sprintf("%s (above %dms)", msg, cfg.WarningMS), Metrics: metrics}
+	}
+
+	return checkers.Result{Status: "healthy", Message: msg, Metrics: metrics}
+}
+
+// Validate checks that a stored configuration can be used by the DNS check
+func (c *Checker) Validate(hs models.HostService) error {
+	cfg := Config{RecordType: "A"}
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(cfg.RecordType) {
+	case "A", "AAAA", "CNAME", "MX", "TXT":
+		return nil
+	}
+	return fmt.Errorf("unsupported record type %q", cfg.RecordType)
+}
+
+// resolver returns the system resolver, or one that queries the given nameserver
+func resolver(nameserver string) *net.Resolver {
+	if nameserver == "" {
+		return net.DefaultResolver
+	}
+
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		nameserver = net.JoinHostPort(nameserver, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, nameserver)
+		},
+	}
+}
+
+// lookup resolves a record and returns its normalized, sorted answers
+func lookup(ctx context.Context, r *net.Resolver, recordType, name string) ([]string, error) {
+	var answers []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, mx.Host)
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		// TXT values are compared as they are, without normalizing
+		answers = append(answers, txts...)
+		sort.Strings(answers)
+		return answers, nil
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", recordType)
+	}
+
+	answers = normalize(answers)
+	sort.Strings(answers)
+	return answers, nil
+}
+
+// normalize lower cases names and drops trailing dots, and puts ip addresses in canonical form
+func normalize(values []string) []string {
+	var out []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if ip := net.ParseIP(v); ip != nil {
+			out = append(out, ip.String())
+			continue
+		}
+		out = append(out, strings.TrimSuffix(strings.ToLower(v), "."))
+	}
+	return out
+}
+
+// missingValues returns the expected values that are not among the answers
+func missingValues(expected, answers []string) []string {
+	have := make(map[string]bool)
+	for _, a := range answers {
+		have[a] = true
+	}
+
+	var missing []string
+	for _, e := range expected {
+		if !have[e] {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
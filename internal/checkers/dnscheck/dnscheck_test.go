@@ -0,0 +1,166 @@
+package dnscheck
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/wtran29/spectre/internal/models"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeNameserver answers A and TXT queries for web.test, and NXDOMAIN for any other name
+func fakeNameserver(t *testing.T) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeSuccess},
+				Questions: []dnsmessage.Question{q},
+			}
+			rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+			switch {
+			case q.Name.String() != "web.test.":
+				resp.Header.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeA:
+				rh.Type = dnsmessage.TypeA
+				resp.Answers = []dnsmessage.Resource{
+					{Header: rh, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 20}}},
+					{Header: rh, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}}},
+				}
+			case q.Type == dnsmessage.TypeTXT:
+				rh.Type = dnsmessage.TypeTXT
+				resp.Answers = []dnsmessage.Resource{
+					{Header: rh, Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+					{Header: rh, Body: &dnsmessage.TXTResource{TXT: []string{"google-site-verification=abc"}}},
+				}
+			}
+
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			pc.WriteTo(out, addr)
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func TestChecker_Check(t *testing.T) {
+	ns := fakeNameserver(t)
+	host := models.Host{CanonicalName: "web.test.", IP: "192.0.2.10"}
+
+	var tests = []struct {
+		name            string
+		host            models.Host
+		config          string
+		expected        string
+		expectedMessage string
+	}{
+		{"answers", host, `{"nameserver": "` + ns + `"}`, "healthy", "A web.test. - 192.0.2.10, 192.0.2.20 in"},
+		{"expected", host, `{"nameserver": "` + ns + `", "expected": ["192.0.2.20"]}`, "healthy", "192.0.2.10, 192.0.2.20"},
+		{"expected-missing", host, `{"nameserver": "` + ns + `", "expected": ["192.0.2.30"]}`, "problem", "do not include 192.0.2.30"},
+		{"match-host-ip", host, `{"nameserver": "` + ns + `", "match_host_ip": true}`, "healthy", "192.0.2.10"},
+		{"host-ip-mismatch", models.Host{CanonicalName: "web.test.", IP: "192.0.2.99"}, `{"nameserver": "` + ns + `", "match_host_ip": true}`, "problem", "do not include 192.0.2.99"},
+		{"host-ip-mismatch-by-default", models.Host{CanonicalName: "web.test.", IP: "192.0.2.99"}, `{"nameserver": "` + ns + `"}`, "problem", "do not include 192.0.2.99"},
+		{"match-host-ip-off", models.Host{CanonicalName: "web.test.", IP: "192.0.2.99"}, `{"nameserver": "` + ns + `", "match_host_ip": false}`, "healthy", "192.0.2.10, 192.0.2.20"},
+		{"txt-sorted", host, `{"nameserver": "` + ns + `", "record_type": "txt", "expected": ["v=spf1 -all"]}`, "healthy", "TXT web.test. - google-site-verification=abc, v=spf1 -all in"},
+		{"nxdomain", models.Host{CanonicalName: "missing.test."}, `{"nameserver": "` + ns + `"}`, "problem", "A missing.test. - NXDOMAIN"},
+	}
+
+	for _, e := range tests {
+		hs := models.HostService{Service: models.Services{ServiceName: "DNS"}, Config: e.config}
+		res := (&Checker{}).Check(context.Background(), e.host, hs)
+		if res.Status != e.expected {
+			t.Errorf("%s: expected %s, but got %s (%s)", e.name, e.expected, res.Status, res.Message)
+		}
+		if !strings.Contains(res.Message, e.expectedMessage) {
+			t.Errorf("%s: expected message containing %q, but got %q", e.name, e.expectedMessage, res.Message)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	var tests = []struct {
+		name     string
+		values   []string
+		expected []string
+	}{
+		{"name", []string{" Mail.Example.COM. "}, []string{"mail.example.com"}},
+		{"ipv4", []string{"192.0.2.1"}, []string{"192.0.2.1"}},
+		{"ipv6", []string{"2001:DB8:0:0:0:0:0:1"}, []string{"2001:db8::1"}},
+		{"empty", nil, nil},
+	}
+
+	for _, e := range tests {
+		got := normalize(e.values)
+		if strings.Join(got, ",") != strings.Join(e.expected, ",") {
+			t.Errorf("%s: expected %v, but got %v", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestMissingValues(t *testing.T) {
+	var tests = []struct {
+		name     string
+		expected []string
+		answers  []string
+		missing  []string
+	}{
+		{"all-present", []string{"a", "b"}, []string{"b", "a", "c"}, nil},
+		{"one-missing", []string{"a", "d"}, []string{"a", "b"}, []string{"d"}},
+		{"no-answers", []string{"a"}, nil, []string{"a"}},
+		{"nothing-expected", nil, []string{"a"}, nil},
+	}
+
+	for _, e := range tests {
+		got := missingValues(e.expected, e.answers)
+		if strings.Join(got, ",") != strings.Join(e.missing, ",") {
+			t.Errorf("%s: expected %v, but got %v", e.name, e.missing, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{"default", ``, false},
+		{"lower-case", `{"record_type": "mx"}`, false},
+		{"cname", `{"record_type": "CNAME"}`, false},
+		{"unsupported", `{"record_type": "SRV"}`, true},
+		{"invalid-json", `{"record_type": `, true},
+	}
+
+	for _, e := range tests {
+		err := (&Checker{}).Validate(models.HostService{Config: e.config})
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package execcheck runs Nagios/Monitoring Plugins compatible commands as checks. Only commands on
+// the admin managed allowlist may run; each run gets its own empty working directory and a minimal
+// environment, and is killed (along with anything it started) when the check times out.
+package execcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("Exec", &Checker{})
+}
+
+// maxOutput caps how much of a plugin's output is read
+const maxOutput = 64 * 1024
+
+// sandboxPath is the only PATH a plugin sees
+const sandboxPath = "/usr/local/bin:/usr/bin:/bin"
+
+// Config is the per host service configuration for an exec check. Args may use the $HOSTNAME$ and
+// $HOSTADDRESS$ macros, which are replaced with the host's name and address.
+type Config struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+var (
+	allowMu   sync.RWMutex
+	allowlist = map[string]bool{}
+)
+
+// SetAllowlist replaces the commands exec checks may run. Entries must be absolute paths; anything
+// else is ignored.
+func SetAllowlist(commands []string) {
+	allowed := make(map[string]bool)
+	for _, c := range commands {
+		if !filepath.IsAbs(c) {
+			log.Println("ignoring exec allowlist entry that is not an absolute path:", c)
+			continue
+		}
+		allowed[filepath.Clean(c)] = true
+	}
+
+	allowMu.Lock()
+	allowlist = allowed
+	allowMu.Unlock()
+}
+
+// ParseAllowlist splits the exec_allowlist preference (one command per line; blank lines and lines
+// starting with # are skipped) into commands
+func ParseAllowlist(pref string) []string {
+	var commands []string
+	for _, line := range strings.Split(pref, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		commands = append(commands, line)
+	}
+	return commands
+}
+
+// allowed reports whether command is on the allowlist
+func allowed(command string) bool {
+	allowMu.RLock()
+	defer allowMu.RUnlock()
+	return allowlist[filepath.Clean(command)]
+}
+
+// Checker runs a plugin command for a host service
+type Checker struct{}
+
+// Check performs the exec check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	var cfg Config
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+	if err := validate(cfg); err != nil {
+		return checkers.Result{Status: "unknown", Message: err.Error()}
+	}
+	if !allowed(cfg.Command) {
+		return checkers.Result{Status: "unknown", Message: fmt.Sprintf("%s is not on the exec allowlist", cfg.Command)}
+	}
+
+	dir, err := os.MkdirTemp("", "spectre-exec-")
+	if err != nil {
+		log.Println(err)
+		return checkers.Result{Status: "unknown", Message: "could not create working directory"}
+	}
+	defer os.RemoveAll(dir)
+
+	replacer := strings.NewReplacer("$HOSTNAME$", h.HostName, "$HOSTADDRESS$", checkers.Address(h))
+	args := make([]string, len(cfg.Args))
+	for i, a := range cfg.Args {
+		args[i] = replacer.Replace(a)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cfg.Command, args...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"PATH=" + sandboxPath,
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C",
+	}
+	cmd.Stdin = nil
+	cmd.Stdout = &limitedWriter{w: &stdout, n: maxOutput}
+	cmd.Stderr = &limitedWriter{w: &stderr, n: maxOutput}
+	cmd.WaitDelay = time.Second
+	sandbox(cmd)
+
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return checkers.Result{Status: "problem", Message: checkers.ContextError(ctx, hs).Error()}
+	}
+
+	code := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return checkers.Result{Status: "unknown", Message: fmt.Sprintf("could not run %s: %s", cfg.Command, err)}
+		}
+		code = exitErr.ExitCode()
+	}
+
+	output := stdout.String()
+	if strings.TrimSpace(output) == "" {
+		output = stderr.String()
+	}
+	msg, metrics := parseOutput(output)
+
+	return checkers.Result{Status: statusForExitCode(code), Message: msg, Metrics: metrics}
+}
+
+// Validate checks an exec host service's configuration. The allowlist is checked when the command
+// runs, so an admin can add a command after configuring it.
+func (c *Checker) Validate(hs models.HostService) error {
+	var cfg Config
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return err
+	}
+	if hs.Config == "" || hs.Config == "{}" {
+		return nil
+	}
+	return validate(cfg)
+}
+
+func validate(cfg Config) error {
+	if cfg.Command == "" {
+		return errors.New("no command configured")
+	}
+	if !filepath.IsAbs(cfg.Command) {
+		return fmt.Errorf("command %s must be an absolute path", cfg.Command)
+	}
+	return nil
+}
+
+// statusForExitCode maps a plugin exit code to a status, as Nagios does
+func statusForExitCode(code int) string {
+	switch code {
+	case 0:
+		return "healthy"
+	case 1:
+		return "warning"
+	case 2:
+		return "problem"
+	default:
+		return "unknown"
+	}
+}
+
+// limitedWriter discards everything written after the first n bytes, without failing the command
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n > 0 {
+		b := p
+		if len(b) > l.n {
+			b = b[:l.n]
+		}
+		n, err := l.w.Write(b)
+		l.n -= n
+		if err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package execcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+var checkTests = []struct {
+	name            string
+	config          string
+	expectedStatus  string
+	expectedMessage string
+}{
+	{"ok", `{"command": "/bin/sh", "args": ["-c", "echo 'OK - fine | t=1'"]}`, "healthy", "OK - fine"},
+	{"warning", `{"command": "/bin/sh", "args": ["-c", "echo WARN; exit 1"]}`, "warning", "WARN"},
+	{"critical", `{"command": "/bin/sh", "args": ["-c", "echo CRIT; exit 2"]}`, "problem", "CRIT"},
+	{"unknown", `{"command": "/bin/sh", "args": ["-c", "echo UNK; exit 3"]}`, "unknown", "UNK"},
+	{"other-exit-code", `{"command": "/bin/sh", "args": ["-c", "exit 7"]}`, "unknown", "(no output returned from plugin)"},
+	{"macros", `{"command": "/bin/sh", "args": ["-c", "echo $0", "$HOSTNAME$"]}`, "healthy", "web1"},
+	{"restricted-env", `{"command": "/bin/sh", "args": ["-c", "echo \"[$SECRET]\""]}`, "healthy", "[]"},
+	{"not-allowed", `{"command": "/bin/echo", "args": ["hi"]}`, "unknown", "/bin/echo is not on the exec allowlist"},
+	{"relative", `{"command": "sh"}`, "unknown", "command sh must be an absolute path"},
+	{"timeout", `{"command": "/bin/sh", "args": ["-c", "sleep 5"], "timeout_seconds": 1}`, "problem", "timed out after 1s"},
+}
+
+func TestCheck(t *testing.T) {
+	t.Setenv("SECRET", "leaked")
+	SetAllowlist(ParseAllowlist("# plugins\n/bin/sh\n\nrelative/ignored\n"))
+	defer SetAllowlist(nil)
+
+	var c Checker
+	h := models.Host{HostName: "web1", IP: "10.0.0.1"}
+	for _, e := range checkTests {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		res := c.Check(ctx, h, models.HostService{Config: e.config})
+		cancel()
+
+		if res.Status != e.expectedStatus {
+			t.Errorf("%s: expected status %s, but got %s (%s)", e.name, e.expectedStatus, res.Status, res.Message)
+		}
+		if res.Message != e.expectedMessage {
+			t.Errorf("%s: expected message %q, but got %q", e.name, e.expectedMessage, res.Message)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package execcheck
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseOutput splits plugin output into the message shown for the host service (the text before |
+// on the first line) and the metrics in its performance data. Perfdata may follow | on the first
+// line and on any later line, as in the plugin development guidelines.
+func parseOutput(output string) (string, map[string]float64) {
+	lines := strings.Split(strings.TrimRight(output, "\r\n"), "\n")
+
+	msg, perf, _ := strings.Cut(lines[0], "|")
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		msg = "(no output returned from plugin)"
+	}
+
+	perfdata := []string{perf}
+	for _, line := range lines[1:] {
+		if _, p, ok := strings.Cut(line, "|"); ok {
+			perfdata = append(perfdata, p)
+		}
+	}
+
+	metrics := make(map[string]float64)
+	for _, p := range perfdata {
+		for k, v := range parsePerfdata(p) {
+			metrics[k] = v
+		}
+	}
+	if len(metrics) == 0 {
+		metrics = nil
+	}
+
+	return msg, metrics
+}
+
+// parsePerfdata parses space separated 'label'=value[UOM];[warn];[crit];[min];[max] items, keeping
+// the label and value. Items that can't be parsed are skipped.
+func parsePerfdata(s string) map[string]float64 {
+	metrics := make(map[string]float64)
+
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var label string
+		if strings.HasPrefix(s, "'") {
+			end := strings.Index(s[1:], "'=")
+			if end < 0 {
+				break
+			}
+			label = strings.ReplaceAll(s[1:end+1], "''", "'")
+			s = s[end+3:]
+		} else {
+			eq := strings.Index(s, "=")
+			if eq < 0 {
+				break
+			}
+			label = s[:eq]
+			s = s[eq+1:]
+		}
+
+		item := s
+		if sp := strings.IndexAny(s, " \t"); sp >= 0 {
+			item, s = s[:sp], strings.TrimSpace(s[sp:])
+		} else {
+			s = ""
+		}
+
+		value, _, _ := strings.Cut(item, ";")
+		value = strings.TrimRightFunc(value, func(r rune) bool {
+			return !(r >= '0' && r <= '9') && r != '.'
+		})
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || strings.TrimSpace(label) == "" {
+			continue
+		}
+		metrics[strings.TrimSpace(label)] = v
+	}
+
+	return metrics
+}
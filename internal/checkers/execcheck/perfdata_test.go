@@ -0,0 +1,48 @@
+package execcheck
+
+import (
+	"reflect"
+	"testing"
+)
+
+var outputTests = []struct {
+	name            string
+	output          string
+	expectedMessage string
+	expectedMetrics map[string]float64
+}{
+	{"plain", "OK - all good\n", "OK - all good", nil},
+	{"empty", "", "(no output returned from plugin)", nil},
+	{
+		"perfdata",
+		"DISK OK - free space: / 3326 MB (56%) | /=2643MB;5948;5958;0;5968\n",
+		"DISK OK - free space: / 3326 MB (56%)",
+		map[string]float64{"/": 2643},
+	},
+	{
+		"quoted-labels",
+		"PING OK|'round trip'=0.42ms;100;500 'it''s'=3 loss=0%",
+		"PING OK",
+		map[string]float64{"round trip": 0.42, "it's": 3, "loss": 0},
+	},
+	{
+		"long-output-perfdata",
+		"LOAD OK | load1=0.5;2;4\nload details\nmore | load5=0.25 load15=0.1",
+		"LOAD OK",
+		map[string]float64{"load1": 0.5, "load5": 0.25, "load15": 0.1},
+	},
+	{"unknown-value", "OK | temp=U;30;40", "OK", nil},
+	{"negative", "OK | offset=-0.015s;1;2", "OK", map[string]float64{"offset": -0.015}},
+}
+
+func TestParseOutput(t *testing.T) {
+	for _, e := range outputTests {
+		msg, metrics := parseOutput(e.output)
+		if msg != e.expectedMessage {
+			t.Errorf("%s: expected message %q, but got %q", e.name, e.expectedMessage, msg)
+		}
+		if !reflect.DeepEqual(metrics, e.expectedMetrics) {
+			t.Errorf("%s: expected metrics %v, but got %v", e.name, e.expectedMetrics, metrics)
+		}
+	}
+}
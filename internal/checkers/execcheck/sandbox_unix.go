@@ -0,0 +1,17 @@
+//go:build unix
+
+package execcheck
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// sandbox runs the command in its own process group, so a timeout kills any children it started
+// as well
+func sandbox(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
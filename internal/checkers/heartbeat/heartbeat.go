@@ -0,0 +1,88 @@
+// Package heartbeat implements push (dead man's switch) monitors for jobs that cannot be polled.
+// A job calls its host service's secret ping url when it finishes; the scheduled check then only
+// looks at when the last ping arrived and whether it reported success.
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("Heartbeat", &Checker{})
+}
+
+// Config is the per host service configuration for a heartbeat monitor: a ping is expected every
+// PeriodMinutes, and the service becomes a problem once GraceMinutes more have passed without one
+type Config struct {
+	PeriodMinutes int `json:"period_minutes"`
+	GraceMinutes  int `json:"grace_minutes"`
+}
+
+// Checker reports on the last ping received for a host service
+type Checker struct{}
+
+// Check performs the heartbeat check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	cfg := Config{
+		PeriodMinutes: 1440,
+		GraceMinutes:  60,
+	}
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+
+	return evaluate(cfg, hs, time.Now())
+}
+
+// evaluate returns the status of a heartbeat monitor at a point in time
+func evaluate(cfg Config, hs models.HostService, now time.Time) checkers.Result {
+	deadline := time.Duration(cfg.PeriodMinutes+cfg.GraceMinutes) * time.Minute
+
+	if hs.LastHeartbeat.IsZero() || hs.LastHeartbeat.Year() <= 1 {
+		// a job that never pings is overdue once a full period and grace have passed since
+		// monitoring was turned on
+		since := hs.ActivatedAt
+		if since.IsZero() || since.Year() <= 1 {
+			since = hs.CreatedAt
+		}
+		if !since.IsZero() && since.Year() > 1 && now.Sub(since) > deadline {
+			return checkers.Result{
+				Status: "problem",
+				Message: fmt.Sprintf("no ping since monitoring started at %s (expected every %dm, grace %dm)",
+					since.Format("01-02-2006, 3:04:05 PM"), cfg.PeriodMinutes, cfg.GraceMinutes),
+			}
+		}
+		return checkers.Result{Status: "pending", Message: "waiting for first ping"}
+	}
+
+	last := hs.LastHeartbeat.Format("01-02-2006, 3:04:05 PM")
+	age := now.Sub(hs.LastHeartbeat)
+	metrics := map[string]float64{"since_last_ping_seconds": age.Seconds()}
+
+	if hs.HeartbeatOK != 1 {
+		msg := fmt.Sprintf("job reported failure at %s", last)
+		if hs.HeartbeatMessage != "" {
+			msg = fmt.Sprintf("%s: %s", msg, hs.HeartbeatMessage)
+		}
+		return checkers.Result{Status: "problem", Message: msg, Metrics: metrics}
+	}
+
+	if age > deadline {
+		return checkers.Result{
+			Status:  "problem",
+			Message: fmt.Sprintf("no ping since %s (expected every %dm, grace %dm)", last, cfg.PeriodMinutes, cfg.GraceMinutes),
+			Metrics: metrics,
+		}
+	}
+
+	msg := fmt.Sprintf("last ping at %s", last)
+	if hs.HeartbeatMessage != "" {
+		msg = fmt.Sprintf("%s: %s", msg, hs.HeartbeatMessage)
+	}
+	return checkers.Result{Status: "healthy", Message: msg, Metrics: metrics}
+}
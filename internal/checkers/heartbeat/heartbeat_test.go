@@ -0,0 +1,35 @@
+package heartbeat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	cfg := Config{PeriodMinutes: 60, GraceMinutes: 10}
+
+	var tests = []struct {
+		name     string
+		hs       models.HostService
+		expected string
+	}{
+		{"never-pinged", models.HostService{}, "pending"},
+		{"never-pinged-within-grace", models.HostService{ActivatedAt: now.Add(-65 * time.Minute)}, "pending"},
+		{"never-pinged-overdue", models.HostService{ActivatedAt: now.Add(-100 * time.Minute)}, "problem"},
+		{"never-pinged-reactivated", models.HostService{CreatedAt: now.Add(-48 * time.Hour), ActivatedAt: now.Add(-10 * time.Minute)}, "pending"},
+		{"never-pinged-created-overdue", models.HostService{CreatedAt: now.Add(-100 * time.Minute)}, "problem"},
+		{"recent-success", models.HostService{LastHeartbeat: now.Add(-30 * time.Minute), HeartbeatOK: 1}, "healthy"},
+		{"within-grace", models.HostService{LastHeartbeat: now.Add(-65 * time.Minute), HeartbeatOK: 1}, "healthy"},
+		{"overdue", models.HostService{LastHeartbeat: now.Add(-71 * time.Minute), HeartbeatOK: 1}, "problem"},
+		{"reported-failure", models.HostService{LastHeartbeat: now.Add(-time.Minute), HeartbeatOK: 0}, "problem"},
+	}
+
+	for _, e := range tests {
+		if got := evaluate(cfg, e.hs, now); got.Status != e.expected {
+			t.Errorf("%s: expected %s, but got %s (%s)", e.name, e.expected, got.Status, got.Message)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package httpcheck
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// maxReadBytes caps how much of a response body is read when no maximum size is configured
+const maxReadBytes = 10 << 20
+
+// Assertions are the content checks applied to a response once its status code is accepted
+type Assertions struct {
+	BodyContains    string            `json:"body_contains"`
+	BodyNotContains string            `json:"body_not_contains"`
+	BodyRegex       string            `json:"body_regex"`
+	HeaderEquals    map[string]string `json:"header_equals"`
+	MaxBodyBytes    int64             `json:"max_body_bytes"`
+}
+
+// readLimit returns the number of body bytes to read; one more than the allowed maximum, so an
+// oversized body can be detected without reading all of it
+func (a Assertions) readLimit() int64 {
+	if a.MaxBodyBytes > 0 {
+		return a.MaxBodyBytes + 1
+	}
+	return maxReadBytes
+}
+
+// check returns an error describing the first assertion that the response fails
+func (a Assertions) check(header http.Header, body []byte) error {
+	if a.MaxBodyBytes > 0 && int64(len(body)) > a.MaxBodyBytes {
+		return fmt.Errorf("body larger than %d bytes", a.MaxBodyBytes)
+	}
+
+	if a.BodyContains != "" && !bytes.Contains(body, []byte(a.BodyContains)) {
+		return fmt.Errorf("body does not contain %q", a.BodyContains)
+	}
+
+	if a.BodyNotContains != "" && bytes.Contains(body, []byte(a.BodyNotContains)) {
+		return fmt.Errorf("body contains %q", a.BodyNotContains)
+	}
+
+	if a.BodyRegex != "" {
+		re, err := regexp.Compile(a.BodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid body regex %q: %s", a.BodyRegex, err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("body does not match %q", a.BodyRegex)
+		}
+	}
+
+	for name, want := range a.HeaderEquals {
+		if got := header.Get(name); got != want {
+			return fmt.Errorf("header %s is %q, expected %q", name, got, want)
+		}
+	}
+
+	return nil
+}
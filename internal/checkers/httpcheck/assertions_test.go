@@ -0,0 +1,41 @@
+package httpcheck
+
+import (
+	"net/http"
+	"testing"
+)
+
+var assertionTests = []struct {
+	name       string
+	assertions Assertions
+	body       string
+	expectFail bool
+}{
+	{"none", Assertions{}, "anything", false},
+	{"contains", Assertions{BodyContains: "ok"}, `{"status":"ok"}`, false},
+	{"contains-missing", Assertions{BodyContains: "ok"}, "maintenance", true},
+	{"not-contains", Assertions{BodyNotContains: "maintenance"}, "all good", false},
+	{"not-contains-present", Assertions{BodyNotContains: "maintenance"}, "down for maintenance", true},
+	{"regex", Assertions{BodyRegex: `version \d+\.\d+`}, "version 1.2", false},
+	{"regex-no-match", Assertions{BodyRegex: `version \d+\.\d+`}, "version x", true},
+	{"regex-invalid", Assertions{BodyRegex: `(`}, "", true},
+	{"header", Assertions{HeaderEquals: map[string]string{"X-App": "spectre"}}, "", false},
+	{"header-wrong", Assertions{HeaderEquals: map[string]string{"X-App": "other"}}, "", true},
+	{"max-size", Assertions{MaxBodyBytes: 5}, "12345", false},
+	{"max-size-exceeded", Assertions{MaxBodyBytes: 5}, "123456", true},
+}
+
+func TestAssertions(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-App", "spectre")
+
+	for _, e := range assertionTests {
+		err := e.assertions.check(header, []byte(e.body))
+		if e.expectFail && err == nil {
+			t.Errorf("%s: expected assertion to fail", e.name)
+		}
+		if !e.expectFail && err != nil {
+			t.Errorf("%s: unexpected failure: %s", e.name, err)
+		}
+	}
+}
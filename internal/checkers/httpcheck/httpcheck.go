@@ -0,0 +1,152 @@
+// Package httpcheck implements the HTTP and HTTPS service checks
+package httpcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("HTTP", &Checker{Scheme: "http"})
+	checkers.Register("HTTPS", &Checker{Scheme: "https"})
+}
+
+// Config is the per host service configuration for an HTTP(S) check
+type Config struct {
+	Request
+	Assertions
+	Thresholds
+	JSONAssertions []JSONAssertion `json:"json_assertions"`
+}
+
+// Checker requests a host's URL over the given scheme and checks the response
+type Checker struct {
+	Scheme string
+}
+
+// Check performs the HTTP(S) check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	var cfg Config
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+
+	accepted, err := cfg.acceptedRanges()
+	if err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+
+	url := cfg.url(targetURL(h.URL, c.Scheme))
+
+	var t timings
+	ctx = httptrace.WithClientTrace(ctx, t.trace())
+
+	req, err := http.NewRequestWithContext(ctx, cfg.method(), url, cfg.body())
+	if err != nil {
+		return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - %s", url, err)}
+	}
+	cfg.apply(req)
+
+	client := probeClient()
+	if !cfg.followRedirects() {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	t.start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(strings.ToUpper(c.Scheme), "error connecting to", url, err)
+		if ctx.Err() != nil {
+			return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - %s", url, checkers.ContextError(ctx, hs))}
+		}
+		return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - %s", url, "error connecting")}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, cfg.readLimit()))
+	t.total = time.Since(t.start)
+	res := checkers.Result{Metrics: t.metrics()}
+	if err != nil {
+		res.Status = "problem"
+		if ctx.Err() != nil {
+			err = checkers.ContextError(ctx, hs)
+		}
+		res.Message = fmt.Sprintf("%s - error reading body: %s (%s)", url, err, &t)
+		return res
+	}
+
+	res.Status, res.Message = evaluate(cfg, accepted, url, resp, body)
+	if s := cfg.Thresholds.status(t.total); s == "problem" || (s == "warning" && res.Status == "healthy") {
+		res.Status = s
+		res.Message = fmt.Sprintf("%s - slow response", res.Message)
+	}
+	res.Message = fmt.Sprintf("%s (%s)", res.Message, &t)
+
+	return res
+}
+
+// evaluate returns the status and message for a response
+func evaluate(cfg Config, accepted []statusRange, url string, resp *http.Response, body []byte) (string, string) {
+	if !accepts(accepted, resp.StatusCode) {
+		return "problem", fmt.Sprintf("%s - %s", url, resp.Status)
+	}
+
+	if err := cfg.check(resp.Header, body); err != nil {
+		return "problem", fmt.Sprintf("%s - %s - assertion failed: %s", url, resp.Status, err)
+	}
+
+	if len(cfg.JSONAssertions) > 0 {
+		status, failures := checkJSON(cfg.JSONAssertions, body)
+		if status != "" {
+			return status, fmt.Sprintf("%s - %s - json assertion failed: %s", url, resp.Status, strings.Join(failures, "; "))
+		}
+	}
+
+	return "healthy", fmt.Sprintf("%s - %s", url, resp.Status)
+}
+
+// Validate checks that a stored configuration can be used by the HTTP(S) check
+func (c *Checker) Validate(hs models.HostService) error {
+	var cfg Config
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return err
+	}
+
+	if _, err := cfg.acceptedRanges(); err != nil {
+		return err
+	}
+
+	if cfg.BodyRegex != "" {
+		if _, err := regexp.Compile(cfg.BodyRegex); err != nil {
+			return fmt.Errorf("invalid body regex %q: %s", cfg.BodyRegex, err)
+		}
+	}
+
+	for _, a := range cfg.JSONAssertions {
+		if _, err := parseJSONExpr(a.Expression); err != nil {
+			return fmt.Errorf("invalid json assertion %q: %s", a.Expression, err)
+		}
+	}
+
+	return nil
+}
+
+// targetURL rewrites a host url to use the given scheme, without a trailing slash
+func targetURL(url, scheme string) string {
+	url = strings.TrimSuffix(url, "/")
+	url = strings.TrimPrefix(url, "https://")
+	url = strings.TrimPrefix(url, "http://")
+	return fmt.Sprintf("%s://%s", scheme, url)
+}
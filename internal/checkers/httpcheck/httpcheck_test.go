@@ -0,0 +1,110 @@
+package httpcheck
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func newTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("authorized"))
+	})
+
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != "ping" || r.Header.Get("X-Hook") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(60 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/healthz", http.StatusMovedPermanently)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+var checkTests = []struct {
+	name           string
+	config         string
+	expectedStatus string
+}{
+	{"bare-get", `{}`, "problem"},
+	{"health-endpoint", `{"path": "/healthz", "body_contains": "ok"}`, "healthy"},
+	{"health-assertion-fails", `{"path": "/healthz", "body_contains": "down"}`, "problem"},
+	{"bearer", `{"path": "/api", "bearer_token": "secret"}`, "healthy"},
+	{"bearer-missing", `{"path": "/api"}`, "problem"},
+	{"post-webhook", `{"method": "post", "path": "/hook", "body": "ping", "headers": {"X-Hook": "1"}, "accepted_status": "200-299"}`, "healthy"},
+	{"redirect-followed", `{"path": "/old"}`, "healthy"},
+	{"redirect-not-followed", `{"path": "/old", "follow_redirects": false}`, "problem"},
+	{"redirect-accepted", `{"path": "/old", "follow_redirects": false, "accepted_status": "301"}`, "healthy"},
+	{"slow-within-thresholds", `{"path": "/slow", "warning_ms": 2000, "critical_ms": 5000}`, "healthy"},
+	{"slow-warning", `{"path": "/slow", "warning_ms": 20}`, "warning"},
+	{"slow-critical", `{"path": "/slow", "warning_ms": 10, "critical_ms": 20}`, "problem"},
+	{"bad-status-setting", `{"path": "/healthz", "accepted_status": "abc"}`, "problem"},
+}
+
+func TestChecker_Check(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	c := &Checker{Scheme: "http"}
+	h := models.Host{URL: srv.URL}
+
+	for _, e := range checkTests {
+		hs := models.HostService{Config: e.config}
+		res := c.Check(context.Background(), h, hs)
+		if res.Status != e.expectedStatus {
+			t.Errorf("%s: expected %s, but got %s (%s)", e.name, e.expectedStatus, res.Status, res.Message)
+		}
+		if res.Status != "problem" || res.Metrics != nil {
+			if _, ok := res.Metrics["total_ms"]; !ok {
+				t.Errorf("%s: expected total_ms metric", e.name)
+			}
+		}
+	}
+}
+
+func TestChecker_CheckTimeout(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	c := &Checker{Scheme: "http"}
+	h := models.Host{URL: srv.URL}
+	hs := models.HostService{Config: `{"path": "/slow", "timeout_seconds": 1}`}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	res := c.Check(ctx, h, hs)
+	if res.Status != "problem" {
+		t.Errorf("expected problem, but got %s", res.Status)
+	}
+	if !strings.Contains(res.Message, "timed out after 1s") {
+		t.Errorf("expected timeout message, but got %q", res.Message)
+	}
+}
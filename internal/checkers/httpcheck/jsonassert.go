@@ -0,0 +1,255 @@
+package httpcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONAssertion is a JSONPath-style expression evaluated against a json response body, such as
+// $.status == "ok" or $.queue_depth < 1000. A bare path passes when the value exists and is not
+// false or null. Level is the status reported when the assertion fails: warning or problem.
+type JSONAssertion struct {
+	Expression string `json:"expression"`
+	Level      string `json:"level"`
+}
+
+// operators are checked longest first, so <= is not read as <
+var operators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// jsonExpr is a parsed JSONAssertion expression
+type jsonExpr struct {
+	path     []interface{}
+	operator string
+	value    interface{}
+}
+
+// level returns the status for a failed assertion, defaulting to problem
+func (a JSONAssertion) level() string {
+	if strings.EqualFold(a.Level, "warning") {
+		return "warning"
+	}
+	return "problem"
+}
+
+// checkJSON evaluates the assertions against a body, returning the worst status of any failures
+// ("" when all pass) and a description of each failure
+func checkJSON(assertions []JSONAssertion, body []byte) (string, []string) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "problem", []string{fmt.Sprintf("body is not valid json: %s", err)}
+	}
+
+	status := ""
+	var failures []string
+	for _, a := range assertions {
+		expr, err := parseJSONExpr(a.Expression)
+		if err == nil {
+			err = expr.eval(doc)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s (%s)", a.Expression, err))
+			if status != "problem" {
+				status = a.level()
+			}
+		}
+	}
+
+	return status, failures
+}
+
+// parseJSONExpr splits an expression into its path, operator and literal value
+func parseJSONExpr(s string) (jsonExpr, error) {
+	var expr jsonExpr
+	s = strings.TrimSpace(s)
+
+	pathPart := s
+	if i, op := findOperator(s); i >= 0 {
+		pathPart = strings.TrimSpace(s[:i])
+		expr.operator = op
+
+		literal := strings.TrimSpace(s[i+len(op):])
+		if strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") && len(literal) > 1 {
+			literal = strconv.Quote(literal[1 : len(literal)-1])
+		}
+		if err := json.Unmarshal([]byte(literal), &expr.value); err != nil {
+			return expr, fmt.Errorf("invalid value %s", literal)
+		}
+	}
+
+	path, err := parseJSONPath(pathPart)
+	if err != nil {
+		return expr, err
+	}
+	expr.path = path
+
+	return expr, nil
+}
+
+// findOperator returns the position of the first comparison operator outside of quotes
+func findOperator(s string) (int, string) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		default:
+			for _, op := range operators {
+				if strings.HasPrefix(s[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+// parseJSONPath parses $.a.b[0]['c d'] into its keys (strings) and indexes (ints)
+func parseJSONPath(s string) ([]interface{}, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("path %q must start with $", s)
+	}
+
+	var path []interface{}
+	rest := s[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path %q", s)
+			}
+			path = append(path, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in path %q", s)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if len(inner) > 1 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				path = append(path, inner[1:len(inner)-1])
+			} else if idx, err := strconv.Atoi(inner); err == nil {
+				path = append(path, idx)
+			} else {
+				return nil, fmt.Errorf("invalid index %q in path %q", inner, s)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in path %q", rest[0], s)
+		}
+	}
+
+	return path, nil
+}
+
+// eval returns an error when the expression does not hold for the document
+func (e jsonExpr) eval(doc interface{}) error {
+	v, err := lookup(doc, e.path)
+	if err != nil {
+		return err
+	}
+
+	switch e.operator {
+	case "":
+		if v == nil || v == false {
+			return fmt.Errorf("value is %v", v)
+		}
+		return nil
+	case "==", "!=":
+		equal := reflect.DeepEqual(v, e.value)
+		if equal != (e.operator == "==") {
+			return fmt.Errorf("got %s", display(v))
+		}
+		return nil
+	}
+
+	cmp, err := compare(v, e.value)
+	if err != nil {
+		return err
+	}
+
+	ok := false
+	switch e.operator {
+	case "<":
+		ok = cmp < 0
+	case "<=":
+		ok = cmp <= 0
+	case ">":
+		ok = cmp > 0
+	case ">=":
+		ok = cmp >= 0
+	}
+	if !ok {
+		return fmt.Errorf("got %s", display(v))
+	}
+	return nil
+}
+
+// lookup walks a decoded json document along a path
+func lookup(doc interface{}, path []interface{}) (interface{}, error) {
+	v := doc
+	for _, p := range path {
+		switch key := p.(type) {
+		case string:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an object", key)
+			}
+			if v, ok = obj[key]; !ok {
+				return nil, fmt.Errorf("%s not found", key)
+			}
+		case int:
+			arr, ok := v.([]interface{})
+			if !ok || key < 0 || key >= len(arr) {
+				return nil, fmt.Errorf("index %d not found", key)
+			}
+			v = arr[key]
+		}
+	}
+	return v, nil
+}
+
+// compare orders two numbers or two strings
+func compare(a, b interface{}) (int, error) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	}
+	return 0, errors.New("cannot compare " + display(a) + " with " + display(b))
+}
+
+// display formats a decoded json value for a message
+func display(v interface{}) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(out)
+}
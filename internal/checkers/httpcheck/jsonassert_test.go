@@ -0,0 +1,43 @@
+package httpcheck
+
+import "testing"
+
+const healthDoc = `{"status":"ok","db":"up","queue_depth":1500,"checks":[{"name":"cache","ok":true}],"build info":{"version":"1.2.0"},"maintenance":false}`
+
+var jsonAssertionTests = []struct {
+	name           string
+	assertions     []JSONAssertion
+	expectedStatus string
+}{
+	{"string-equal", []JSONAssertion{{Expression: `$.status == "ok"`}}, ""},
+	{"single-quotes", []JSONAssertion{{Expression: `$.db == 'up'`}}, ""},
+	{"string-not-equal", []JSONAssertion{{Expression: `$.status != "ok"`}}, "problem"},
+	{"number-less", []JSONAssertion{{Expression: `$.queue_depth < 1000`}}, "problem"},
+	{"number-less-warning", []JSONAssertion{{Expression: `$.queue_depth < 1000`, Level: "warning"}}, "warning"},
+	{"number-greater-equal", []JSONAssertion{{Expression: `$.queue_depth >= 1500`}}, ""},
+	{"array-index", []JSONAssertion{{Expression: `$.checks[0].ok == true`}}, ""},
+	{"bracket-key", []JSONAssertion{{Expression: `$['build info'].version == "1.2.0"`}}, ""},
+	{"exists", []JSONAssertion{{Expression: `$.db`}}, ""},
+	{"exists-false", []JSONAssertion{{Expression: `$.maintenance`}}, "problem"},
+	{"missing", []JSONAssertion{{Expression: `$.nope == 1`, Level: "warning"}}, "warning"},
+	{"problem-wins", []JSONAssertion{
+		{Expression: `$.queue_depth < 1000`, Level: "warning"},
+		{Expression: `$.db == "down"`},
+	}, "problem"},
+	{"bad-compare", []JSONAssertion{{Expression: `$.status > 5`}}, "problem"},
+	{"bad-path", []JSONAssertion{{Expression: `status == "ok"`}}, "problem"},
+}
+
+func TestCheckJSON(t *testing.T) {
+	for _, e := range jsonAssertionTests {
+		status, failures := checkJSON(e.assertions, []byte(healthDoc))
+		if status != e.expectedStatus {
+			t.Errorf("%s: expected status %q, but got %q (%v)", e.name, e.expectedStatus, status, failures)
+		}
+	}
+
+	status, _ := checkJSON([]JSONAssertion{{Expression: `$.status == "ok"`}}, []byte("<html>"))
+	if status != "problem" {
+		t.Errorf("non-json body: expected problem, but got %q", status)
+	}
+}
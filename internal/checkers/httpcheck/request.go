@@ -0,0 +1,116 @@
+package httpcheck
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Request describes the request sent to a host by an HTTP(S) check
+type Request struct {
+	Method            string            `json:"method"`
+	Path              string            `json:"path"`
+	Headers           map[string]string `json:"headers"`
+	BasicAuthUser     string            `json:"basic_auth_user"`
+	BasicAuthPassword string            `json:"basic_auth_password"`
+	BearerToken       string            `json:"bearer_token"`
+	Body              string            `json:"body"`
+	FollowRedirects   *bool             `json:"follow_redirects"`
+	AcceptedStatus    string            `json:"accepted_status"`
+}
+
+// statusRange is an inclusive range of accepted status codes
+type statusRange struct {
+	from, to int
+}
+
+// method returns the configured request method, defaulting to GET
+func (r Request) method() string {
+	if r.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(r.Method)
+}
+
+// followRedirects reports whether redirects should be followed; they are unless turned off
+func (r Request) followRedirects() bool {
+	return r.FollowRedirects == nil || *r.FollowRedirects
+}
+
+// url appends the configured path and query to a base url
+func (r Request) url(base string) string {
+	if r.Path == "" {
+		return base
+	}
+	if strings.HasPrefix(r.Path, "/") || strings.HasPrefix(r.Path, "?") {
+		return base + r.Path
+	}
+	return base + "/" + r.Path
+}
+
+// body returns a reader for the configured request body, or nil when there is none
+func (r Request) body() io.Reader {
+	if r.Body == "" {
+		return nil
+	}
+	return strings.NewReader(r.Body)
+}
+
+// apply sets the configured headers and credentials on a request
+func (r Request) apply(req *http.Request) {
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if r.BasicAuthUser != "" {
+		req.SetBasicAuth(r.BasicAuthUser, r.BasicAuthPassword)
+	} else if r.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.BearerToken)
+	}
+}
+
+// acceptedRanges parses the accepted status setting, e.g. "200-299, 301", defaulting to 200 only
+func (r Request) acceptedRanges() ([]statusRange, error) {
+	if strings.TrimSpace(r.AcceptedStatus) == "" {
+		return []statusRange{{200, 200}}, nil
+	}
+
+	var ranges []statusRange
+	for _, part := range strings.Split(r.AcceptedStatus, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid accepted status %q", part)
+		}
+		to := from
+		if len(bounds) == 2 {
+			to, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid accepted status %q", part)
+			}
+		}
+
+		if from < 100 || to > 599 || from > to {
+			return nil, fmt.Errorf("invalid accepted status %q", part)
+		}
+		ranges = append(ranges, statusRange{from, to})
+	}
+	return ranges, nil
+}
+
+// accepts reports whether a status code falls in one of the ranges
+func accepts(ranges []statusRange, code int) bool {
+	for _, r := range ranges {
+		if code >= r.from && code <= r.to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package httpcheck
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+// Thresholds move a check to warning or problem when the full request takes too long
+type Thresholds struct {
+	WarningMS  int `json:"warning_ms"`
+	CriticalMS int `json:"critical_ms"`
+}
+
+// timings records how long each phase of a request took
+type timings struct {
+	start, dnsStart, connectStart, tlsStart time.Time
+
+	dns, connect, tls, firstByte, total time.Duration
+}
+
+// trace returns a client trace that fills in the timings
+func (t *timings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.dns = time.Since(t.dnsStart) },
+		ConnectStart: func(string, string) {
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.connect = time.Since(t.connectStart) },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tls = time.Since(t.tlsStart) },
+		GotFirstResponseByte: func() { t.firstByte = time.Since(t.start) },
+	}
+}
+
+// metrics returns the timings in milliseconds, keyed by phase
+func (t *timings) metrics() map[string]float64 {
+	return map[string]float64{
+		"dns_ms":     ms(t.dns),
+		"connect_ms": ms(t.connect),
+		"tls_ms":     ms(t.tls),
+		"ttfb_ms":    ms(t.firstByte),
+		"total_ms":   ms(t.total),
+	}
+}
+
+// String formats the timings for a check message
+func (t *timings) String() string {
+	parts := []string{
+		fmt.Sprintf("dns %dms", t.dns.Milliseconds()),
+		fmt.Sprintf("connect %dms", t.connect.Milliseconds()),
+	}
+	if t.tls > 0 {
+		parts = append(parts, fmt.Sprintf("tls %dms", t.tls.Milliseconds()))
+	}
+	parts = append(parts,
+		fmt.Sprintf("ttfb %dms", t.firstByte.Milliseconds()),
+		fmt.Sprintf("total %dms", t.total.Milliseconds()),
+	)
+	return strings.Join(parts, ", ")
+}
+
+// status returns the status the total time earns against the thresholds, or "" when within them
+func (th Thresholds) status(total time.Duration) string {
+	switch {
+	case th.CriticalMS > 0 && total > time.Duration(th.CriticalMS)*time.Millisecond:
+		return "problem"
+	case th.WarningMS > 0 && total > time.Duration(th.WarningMS)*time.Millisecond:
+		return "warning"
+	}
+	return ""
+}
+
+// ms converts a duration to fractional milliseconds
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
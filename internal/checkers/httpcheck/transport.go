@@ -0,0 +1,71 @@
+package httpcheck
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TransportOptions tunes the transport shared by every HTTP(S) check
+type TransportOptions struct {
+	// Proxy is the proxy url for checks; when empty, HTTP_PROXY/HTTPS_PROXY/NO_PROXY are used
+	Proxy           string
+	MaxConnsPerHost int
+	MaxIdleConns    int
+	// KeepAlive reuses connections between checks; it is off by default so that every check
+	// measures a fresh dns lookup, connect and tls handshake
+	KeepAlive bool
+}
+
+var (
+	clientMu sync.RWMutex
+	client   = newClient(http.ProxyFromEnvironment, TransportOptions{MaxConnsPerHost: 10, MaxIdleConns: 100})
+)
+
+// Configure replaces the shared probe client with one built from the options
+func Configure(o TransportOptions) error {
+	proxy := http.ProxyFromEnvironment
+	if o.Proxy != "" {
+		u, err := url.Parse(o.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid probe proxy %q: %w", o.Proxy, err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	client = newClient(proxy, o)
+	return nil
+}
+
+// probeClient returns a copy of the shared probe client, which callers may adjust per check
+func probeClient() http.Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return *client
+}
+
+// newClient builds a client for checks. It has no overall timeout, since every check runs with
+// a context deadline.
+func newClient(proxy func(*http.Request) (*url.URL, error), o TransportOptions) *http.Client {
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          o.MaxIdleConns,
+		MaxConnsPerHost:       o.MaxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     !o.KeepAlive,
+	}
+
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,160 @@
+package pingcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// stats summarises one round of echo requests
+type stats struct {
+	sent, received int
+	min, avg, max  time.Duration
+}
+
+// loss returns the percentage of echo requests that got no reply
+func (s stats) loss() float64 {
+	if s.sent == 0 {
+		return 0
+	}
+	return float64(s.sent-s.received) / float64(s.sent) * 100
+}
+
+// listen opens an unprivileged ICMP datagram socket, which on Linux needs the process's group in
+// net.ipv4.ping_group_range. When that is not allowed it falls back to a raw socket, which works
+// when running as root or with CAP_NET_RAW.
+func listen(ip net.IP) (*icmp.PacketConn, bool, error) {
+	network, raw, address := "udp4", "ip4:icmp", "0.0.0.0"
+	if ip.To4() == nil {
+		network, raw, address = "udp6", "ip6:ipv6-icmp", "::"
+	}
+
+	conn, err := icmp.ListenPacket(network, address)
+	if err == nil {
+		return conn, true, nil
+	}
+
+	conn, rawErr := icmp.ListenPacket(raw, address)
+	if rawErr != nil {
+		return nil, false, fmt.Errorf("cannot open icmp socket (is net.ipv4.ping_group_range set?): %w", err)
+	}
+	return conn, false, nil
+}
+
+// ping sends count echo requests to ip, one at a time, waiting up to wait for each reply
+func ping(ctx context.Context, ip net.IP, count int, interval, wait time.Duration) (stats, error) {
+	var s stats
+
+	conn, datagram, err := listen(ip)
+	if err != nil {
+		return s, err
+	}
+	defer conn.Close()
+
+	// close the socket if the check is cancelled, so a blocked read returns
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if datagram {
+		dst = &net.UDPAddr{IP: ip}
+	}
+
+	echoType, replyType, proto := icmp.Type(ipv4.ICMPTypeEcho), icmp.Type(ipv4.ICMPTypeEchoReply), 1
+	if ip.To4() == nil {
+		echoType, replyType, proto = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, 58
+	}
+
+	// a random payload tells our replies apart from those to other checks sharing a raw socket
+	payload := make([]byte, 16)
+	if _, err := rand.Read(payload); err != nil {
+		return s, err
+	}
+
+	var total time.Duration
+	buf := make([]byte, 1500)
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			select {
+			case <-ctx.Done():
+				return s, ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+
+		msg := icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: seq, Data: payload},
+		}
+		out, err := msg.Marshal(nil)
+		if err != nil {
+			return s, err
+		}
+
+		sentAt := time.Now()
+		if _, err := conn.WriteTo(out, dst); err != nil {
+			if ctx.Err() != nil {
+				return s, ctx.Err()
+			}
+			return s, err
+		}
+		s.sent++
+
+		deadline := sentAt.Add(wait)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		_ = conn.SetReadDeadline(deadline)
+
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if ctx.Err() != nil {
+					return s, ctx.Err()
+				}
+				// no reply in time, so this one counts as lost
+				break
+			}
+
+			reply, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil || reply.Type != replyType {
+				continue
+			}
+			echo, ok := reply.Body.(*icmp.Echo)
+			if !ok || echo.Seq != seq || !bytes.Equal(echo.Data, payload) {
+				continue
+			}
+
+			rtt := time.Since(sentAt)
+			if s.received == 0 || rtt < s.min {
+				s.min = rtt
+			}
+			if rtt > s.max {
+				s.max = rtt
+			}
+			total += rtt
+			s.received++
+			break
+		}
+	}
+
+	if s.received > 0 {
+		s.avg = total / time.Duration(s.received)
+	}
+	return s, nil
+}
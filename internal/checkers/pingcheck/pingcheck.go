@@ -0,0 +1,119 @@
+// Package pingcheck implements the ICMP echo (ping) check
+package pingcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("Ping", &Checker{})
+}
+
+// Config is the per host service configuration for a ping check. Loss thresholds are percentages
+// and round trip thresholds apply to the average round trip time.
+type Config struct {
+	Count              int     `json:"count"`
+	IntervalMS         int     `json:"interval_ms"`
+	WaitMS             int     `json:"wait_ms"`
+	WarningLossPercent float64 `json:"warning_loss_percent"`
+	ProblemLossPercent float64 `json:"problem_loss_percent"`
+	WarningRTTMS       int     `json:"warning_rtt_ms"`
+	ProblemRTTMS       int     `json:"problem_rtt_ms"`
+}
+
+// Checker sends echo requests to a host and reports packet loss and round trip times
+type Checker struct{}
+
+// Check performs the ping check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	cfg := Config{
+		Count:              4,
+		IntervalMS:         200,
+		WaitMS:             1000,
+		WarningLossPercent: 20,
+		ProblemLossPercent: 50,
+		WarningRTTMS:       200,
+		ProblemRTTMS:       1000,
+	}
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+	if cfg.Count < 1 {
+		cfg.Count = 1
+	}
+
+	target := checkers.Address(h)
+	ip, err := resolve(ctx, target)
+	if err != nil {
+		return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - %s", target, err)}
+	}
+
+	s, err := ping(ctx, ip, cfg.Count, time.Duration(cfg.IntervalMS)*time.Millisecond, time.Duration(cfg.WaitMS)*time.Millisecond)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = checkers.ContextError(ctx, hs)
+		}
+		return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - %s", ip, err)}
+	}
+
+	metrics := map[string]float64{
+		"loss_percent": s.loss(),
+		"rtt_min_ms":   msFloat(s.min),
+		"rtt_avg_ms":   msFloat(s.avg),
+		"rtt_max_ms":   msFloat(s.max),
+	}
+	msg := fmt.Sprintf("%s - %d/%d received, %.0f%% loss, rtt min/avg/max %.1f/%.1f/%.1f ms",
+		ip, s.received, s.sent, s.loss(), msFloat(s.min), msFloat(s.avg), msFloat(s.max))
+
+	return checkers.Result{Status: cfg.status(s), Message: msg, Metrics: metrics}
+}
+
+// status maps the loss and round trip thresholds to a status
+func (cfg Config) status(s stats) string {
+	loss := s.loss()
+	avg := s.avg
+
+	switch {
+	case s.received == 0,
+		cfg.ProblemLossPercent > 0 && loss >= cfg.ProblemLossPercent,
+		cfg.ProblemRTTMS > 0 && avg > time.Duration(cfg.ProblemRTTMS)*time.Millisecond:
+		return "problem"
+	case cfg.WarningLossPercent > 0 && loss >= cfg.WarningLossPercent,
+		cfg.WarningRTTMS > 0 && avg > time.Duration(cfg.WarningRTTMS)*time.Millisecond:
+		return "warning"
+	}
+	return "healthy"
+}
+
+// resolve returns the ip address for a target, looking up host names
+func resolve(ctx context.Context, target string) (net.IP, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return ip, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	// prefer ipv4, which is what most devices answer on
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			return a.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found")
+	}
+	return addrs[0].IP, nil
+}
+
+// msFloat converts a duration to fractional milliseconds
+func msFloat(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
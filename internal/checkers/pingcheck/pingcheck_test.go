@@ -0,0 +1,53 @@
+package pingcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+var statusTests = []struct {
+	name     string
+	stats    stats
+	expected string
+}{
+	{"all-replies", stats{sent: 4, received: 4, avg: 10 * time.Millisecond}, "healthy"},
+	{"some-loss", stats{sent: 5, received: 4, avg: 10 * time.Millisecond}, "warning"},
+	{"heavy-loss", stats{sent: 4, received: 2, avg: 10 * time.Millisecond}, "problem"},
+	{"no-replies", stats{sent: 4}, "problem"},
+	{"slow", stats{sent: 4, received: 4, avg: 300 * time.Millisecond}, "warning"},
+	{"very-slow", stats{sent: 4, received: 4, avg: 2 * time.Second}, "problem"},
+}
+
+func TestConfig_status(t *testing.T) {
+	cfg := Config{
+		WarningLossPercent: 20,
+		ProblemLossPercent: 50,
+		WarningRTTMS:       200,
+		ProblemRTTMS:       1000,
+	}
+
+	for _, e := range statusTests {
+		if got := cfg.status(e.stats); got != e.expected {
+			t.Errorf("%s: expected %s, but got %s", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestChecker_CheckLoopback(t *testing.T) {
+	conn, _, err := listen(net.ParseIP("127.0.0.1"))
+	if err != nil {
+		t.Skip("icmp sockets not available:", err)
+	}
+	conn.Close()
+
+	c := &Checker{}
+	hs := models.HostService{Config: `{"count": 2, "interval_ms": 10}`}
+	res := c.Check(context.Background(), models.Host{IP: "127.0.0.1"}, hs)
+	if res.Status != "healthy" {
+		t.Errorf("expected healthy, but got %s (%s)", res.Status, res.Message)
+	}
+}
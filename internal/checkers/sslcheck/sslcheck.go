@@ -0,0 +1,262 @@
+// Package sslcheck implements the SSL certificate check: the chain a host presents must verify
+// against the system roots (or the host's own CA bundle), cover the host name, use strong keys and
+// signatures, and not expire soon anywhere in the chain. Mail servers, FTP servers and databases
+// that only offer TLS through STARTTLS are checked by setting the protocol. The check also notices
+// when the leaf certificate is replaced, or comes from a different issuer, between runs.
+package sslcheck
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtran29/spectre/internal/certificateutils"
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("SSL Certificate", &Checker{})
+}
+
+// Thresholds are how close the earliest expiry in the chain may come, in days, before the check
+// reports a warning or a problem
+type Thresholds struct {
+	WarningDays int
+	ProblemDays int
+}
+
+var (
+	defaultsMu sync.RWMutex
+	defaults   = Thresholds{WarningDays: 30, ProblemDays: 7}
+)
+
+// SetDefaultThresholds sets the thresholds used by host services that don't set their own.
+// Values that aren't positive leave the current default in place.
+func SetDefaultThresholds(t Thresholds) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+
+	if t.WarningDays > 0 {
+		defaults.WarningDays = t.WarningDays
+	}
+	if t.ProblemDays > 0 {
+		defaults.ProblemDays = t.ProblemDays
+	}
+}
+
+// ParseThresholds reads the ssl_warning_days and ssl_problem_days preferences. Values that
+// can't be parsed are returned as zero, which SetDefaultThresholds ignores.
+func ParseThresholds(warning, problem string) Thresholds {
+	w, _ := strconv.Atoi(strings.TrimSpace(warning))
+	p, _ := strconv.Atoi(strings.TrimSpace(problem))
+	return Thresholds{WarningDays: w, ProblemDays: p}
+}
+
+// DefaultThresholds returns the thresholds used by host services that don't set their own
+func DefaultThresholds() Thresholds {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return defaults
+}
+
+// Config is the per host service configuration for the SSL certificate check. CABundle (PEM) or
+// CAFile (a PEM file on the server) replace the system roots, for hosts with certificates from a
+// private CA. Protocol is one of certificateutils.Protocols; empty means direct TLS. WarningDays
+// and ProblemDays override the default thresholds.
+type Config struct {
+	Port        int    `json:"port"`
+	Protocol    string `json:"protocol"`
+	ServerName  string `json:"server_name"`
+	CABundle    string `json:"ca_bundle"`
+	CAFile      string `json:"ca_file"`
+	WarningDays int    `json:"warning_days"`
+	ProblemDays int    `json:"problem_days"`
+}
+
+// state is what the check remembers about the leaf certificate between runs
+type state struct {
+	Thumbprint string `json:"thumbprint"`
+	Issuer     string `json:"issuer"`
+}
+
+// Checker fetches a host's certificate chain and reports on its validity
+type Checker struct{}
+
+// Check performs the SSL certificate check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	d := DefaultThresholds()
+	cfg := Config{WarningDays: d.WarningDays, ProblemDays: d.ProblemDays}
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+
+	roots, err := cfg.roots()
+	if err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+
+	serverName := cfg.ServerName
+	if serverName == "" {
+		serverName = checkers.HostName(h)
+	}
+	address := net.JoinHostPort(checkers.HostName(h), strconv.Itoa(port(h, cfg)))
+
+	certs, err := certificateutils.FetchChain(ctx, address, serverName, cfg.Protocol)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = checkers.ContextError(ctx, hs)
+		}
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+
+	now := time.Now()
+	report := certificateutils.VerifyChain(certs, certificateutils.VerifyOptions{
+		ServerName:  serverName,
+		Roots:       roots,
+		Now:         now,
+		WarningDays: cfg.WarningDays,
+		ProblemDays: cfg.ProblemDays,
+	})
+
+	res := result(serverName, report, now)
+	res.State, res.Notices = detectChange(hs.CheckState, report.Leaf)
+
+	// list the certificates the server presented in the certificate inventory
+	for _, c := range certs {
+		cd := certificateutils.NewCertificateDetails(c, now)
+		cd.Source = address
+		res.Certificates = append(res.Certificates, cd)
+	}
+	return res
+}
+
+// detectChange compares the leaf certificate with the one seen on the last run, returning the
+// state to remember and a notice if the certificate or its issuer changed
+func detectChange(previous string, leaf *x509.Certificate) (string, []checkers.Notice) {
+	current := state{
+		Thumbprint: certificateutils.Thumbprint(leaf),
+		Issuer:     leaf.Issuer.String(),
+	}
+	b, _ := json.Marshal(current)
+
+	var last state
+	if previous == "" || json.Unmarshal([]byte(previous), &last) != nil || last.Thumbprint == "" {
+		return string(b), nil
+	}
+
+	var changes []string
+	if last.Issuer != current.Issuer {
+		changes = append(changes, fmt.Sprintf("issuer changed from %s to %s", last.Issuer, current.Issuer))
+	}
+	if last.Thumbprint != current.Thumbprint {
+		changes = append(changes, fmt.Sprintf("thumbprint changed from %s to %s", last.Thumbprint, current.Thumbprint))
+	}
+	if len(changes) == 0 {
+		return string(b), nil
+	}
+
+	return string(b), []checkers.Notice{{
+		Type:    "certificate-changed",
+		Message: fmt.Sprintf("certificate %q replaced: %s", leaf.Subject.CommonName, strings.Join(changes, "; ")),
+	}}
+}
+
+// result turns a chain report into the outcome of the check
+func result(serverName string, report certificateutils.ChainReport, now time.Time) checkers.Result {
+	days := report.DaysUntilExpiry(now)
+	metrics := map[string]float64{"days_until_expiry": float64(days)}
+
+	if len(report.Findings) == 0 {
+		msg := fmt.Sprintf("%s expiring in %d days", serverName, days)
+		if report.EarliestExpiry != report.Leaf {
+			msg = fmt.Sprintf("%s (chain certificate %s)", msg, report.EarliestExpiry.Subject.CommonName)
+		}
+		return checkers.Result{Status: "healthy", Message: msg, Metrics: metrics}
+	}
+
+	var messages []string
+	for _, f := range report.Findings {
+		messages = append(messages, f.Message)
+	}
+	return checkers.Result{
+		Status:  report.Status(),
+		Message: fmt.Sprintf("%s: %s", serverName, strings.Join(messages, "; ")),
+		Metrics: metrics,
+	}
+}
+
+// Validate checks that a configured CA bundle can be loaded
+func (c *Checker) Validate(hs models.HostService) error {
+	var cfg Config
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return err
+	}
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return fmt.Errorf("invalid port %d", cfg.Port)
+	}
+	if cfg.Protocol != "" && certificateutils.DefaultPort(cfg.Protocol) == 0 {
+		return fmt.Errorf("unsupported protocol %q, must be one of %s", cfg.Protocol, strings.Join(certificateutils.Protocols(), ", "))
+	}
+	if cfg.WarningDays < 0 || cfg.ProblemDays < 0 {
+		return errors.New("warning_days and problem_days must not be negative")
+	}
+	if cfg.WarningDays > 0 && cfg.ProblemDays > cfg.WarningDays {
+		return errors.New("problem_days must not be more than warning_days")
+	}
+	_, err := cfg.roots()
+	return err
+}
+
+// roots returns the trusted roots for the host service, or nil for the system roots
+func (cfg Config) roots() (*x509.CertPool, error) {
+	pem := []byte(cfg.CABundle)
+	if cfg.CAFile != "" {
+		b, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %w", err)
+		}
+		pem = append(pem, '\n')
+		pem = append(pem, b...)
+	}
+	if strings.TrimSpace(string(pem)) == "" {
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("CA bundle contains no certificates")
+	}
+	return pool, nil
+}
+
+// port returns the port to connect to: the configured one, then for direct TLS the one in the
+// host's URL, and otherwise the protocol's usual port
+func port(h models.Host, cfg Config) int {
+	if cfg.Port > 0 {
+		return cfg.Port
+	}
+	if cfg.Protocol != "" && cfg.Protocol != certificateutils.ProtocolTLS {
+		return certificateutils.DefaultPort(cfg.Protocol)
+	}
+
+	raw := strings.TrimSpace(h.URL)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && u.Port() != "" {
+		if p, err := strconv.Atoi(u.Port()); err == nil {
+			return p
+		}
+	}
+	return 443
+}
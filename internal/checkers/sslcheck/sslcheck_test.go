@@ -0,0 +1,122 @@
+package sslcheck
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// selfSigned creates a certificate for www.example.com issued by (and signed as) issuer
+func selfSigned(t *testing.T, issuer string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		Issuer:       pkix.Name{CommonName: issuer},
+		NotBefore:    time.Now().AddDate(0, 0, -1),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+	}
+	parent := &x509.Certificate{Subject: pkix.Name{CommonName: issuer}}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestDetectChange(t *testing.T) {
+	first := selfSigned(t, "Issuer A")
+	renewed := selfSigned(t, "Issuer A")
+	moved := selfSigned(t, "Issuer B")
+
+	firstState, _ := detectChange("", first)
+
+	var tests = []struct {
+		name         string
+		previous     string
+		leaf         *x509.Certificate
+		expectNotice bool
+		expectedText string
+	}{
+		{"first-run", "", first, false, ""},
+		{"unchanged", firstState, first, false, ""},
+		{"renewed", firstState, renewed, true, "thumbprint changed"},
+		{"new-issuer", firstState, moved, true, "issuer changed from CN=Issuer A to CN=Issuer B"},
+		{"unreadable-state", "not json", first, false, ""},
+	}
+
+	for _, e := range tests {
+		state, notices := detectChange(e.previous, e.leaf)
+
+		if state == "" {
+			t.Errorf("%s: expected state to be recorded, but got none", e.name)
+		}
+		if e.expectNotice != (len(notices) == 1) {
+			t.Errorf("%s: expected notice %t, but got %v", e.name, e.expectNotice, notices)
+			continue
+		}
+		if e.expectNotice {
+			if notices[0].Type != "certificate-changed" {
+				t.Errorf("%s: expected notice type certificate-changed, but got %s", e.name, notices[0].Type)
+			}
+			if !strings.Contains(notices[0].Message, e.expectedText) {
+				t.Errorf("%s: expected notice containing %q, but got %q", e.name, e.expectedText, notices[0].Message)
+			}
+		}
+	}
+}
+
+func TestValidateThresholds(t *testing.T) {
+	var tests = []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{"defaults", `{}`, false},
+		{"custom", `{"warning_days": 60, "problem_days": 14}`, false},
+		{"only-problem", `{"problem_days": 3}`, false},
+		{"negative", `{"warning_days": -1}`, true},
+		{"problem-after-warning", `{"warning_days": 10, "problem_days": 20}`, true},
+	}
+
+	c := &Checker{}
+	for _, e := range tests {
+		err := c.Validate(models.HostService{Config: e.config})
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
+
+func TestSetDefaultThresholds(t *testing.T) {
+	defer SetDefaultThresholds(DefaultThresholds())
+
+	SetDefaultThresholds(ParseThresholds("45", "10"))
+	if d := DefaultThresholds(); d.WarningDays != 45 || d.ProblemDays != 10 {
+		t.Errorf("expected 45/10, but got %d/%d", d.WarningDays, d.ProblemDays)
+	}
+
+	SetDefaultThresholds(ParseThresholds("", "junk"))
+	if d := DefaultThresholds(); d.WarningDays != 45 || d.ProblemDays != 10 {
+		t.Errorf("expected unparseable values to be ignored, but got %d/%d", d.WarningDays, d.ProblemDays)
+	}
+}
@@ -0,0 +1,69 @@
+// Package tcpcheck implements the TCP port connectivity check
+package tcpcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+func init() {
+	checkers.Register("TCP", &Checker{})
+}
+
+// dial opens the connection; tests replace it to slow down or stall connecting
+var dial = (&net.Dialer{}).DialContext
+
+// Config is the per host service configuration for a TCP check
+type Config struct {
+	Port      int `json:"port"`
+	WarningMS int `json:"warning_ms"`
+}
+
+// Checker opens a TCP connection to a port on the host and reports the connect latency
+type Checker struct{}
+
+// Check performs the TCP check for a host service
+func (c *Checker) Check(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	cfg := Config{
+		WarningMS: 500,
+	}
+	if err := checkers.DecodeConfig(hs, &cfg); err != nil {
+		return checkers.Result{Status: "problem", Message: err.Error()}
+	}
+
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		return checkers.Result{Status: "problem", Message: "no valid port configured for TCP check"}
+	}
+
+	address := net.JoinHostPort(checkers.Address(h), strconv.Itoa(cfg.Port))
+
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", address)
+	latency := time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - %s", address, checkers.ContextError(ctx, hs))}
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - timed out", address)}
+		}
+		return checkers.Result{Status: "problem", Message: fmt.Sprintf("%s - connection failed: %s", address, err)}
+	}
+	_ = conn.Close()
+
+	msg := fmt.Sprintf("%s - connected in %dms", address, latency.Milliseconds())
+	metrics := map[string]float64{"connect_ms": float64(latency.Microseconds()) / 1000}
+	if cfg.WarningMS > 0 && latency > time.Duration(cfg.WarningMS)*time.Millisecond {
+		return checkers.Result{Status: "warning", Message: fmt.Sprintf("%s (above %dms)", msg, cfg.WarningMS), Metrics: metrics}
+	}
+
+	return checkers.Result{Status: "healthy", Message: msg, Metrics: metrics}
+}
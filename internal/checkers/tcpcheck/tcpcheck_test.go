@@ -0,0 +1,122 @@
+package tcpcheck
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// listen starts a listener on a loopback port that accepts and closes connections, and returns
+// its port
+func listen(t *testing.T) (net.Listener, int) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return l, l.Addr().(*net.TCPAddr).Port
+}
+
+func hostService(config string) models.HostService {
+	return models.HostService{Service: models.Services{ServiceName: "TCP"}, Config: config}
+}
+
+func TestChecker_Check(t *testing.T) {
+	l, port := listen(t)
+	defer l.Close()
+
+	// a port nothing listens on
+	closed, refusedPort := listen(t)
+	closed.Close()
+
+	var tests = []struct {
+		name            string
+		config          string
+		expected        string
+		expectedMessage string
+	}{
+		{"connected", `{"port": ` + strconv.Itoa(port) + `}`, "healthy", "connected in"},
+		{"refused", `{"port": ` + strconv.Itoa(refusedPort) + `}`, "problem", "connection failed"},
+		{"no-port", `{}`, "problem", "no valid port"},
+		{"port-out-of-range", `{"port": 70000}`, "problem", "no valid port"},
+		{"bad-config", `{"port": "eighty"}`, "problem", "invalid configuration"},
+	}
+
+	for _, e := range tests {
+		res := (&Checker{}).Check(context.Background(), models.Host{IP: "127.0.0.1"}, hostService(e.config))
+		if res.Status != e.expected {
+			t.Errorf("%s: expected %s, but got %s (%s)", e.name, e.expected, res.Status, res.Message)
+		}
+		if !strings.Contains(res.Message, e.expectedMessage) {
+			t.Errorf("%s: expected message containing %q, but got %q", e.name, e.expectedMessage, res.Message)
+		}
+	}
+}
+
+func TestChecker_CheckLatencyWarning(t *testing.T) {
+	l, port := listen(t)
+	defer l.Close()
+
+	realDial := dial
+	defer func() { dial = realDial }()
+	dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		time.Sleep(30 * time.Millisecond)
+		return realDial(ctx, network, address)
+	}
+
+	var tests = []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{"above-threshold", `{"port": ` + strconv.Itoa(port) + `, "warning_ms": 10}`, "warning"},
+		{"below-threshold", `{"port": ` + strconv.Itoa(port) + `, "warning_ms": 1000}`, "healthy"},
+		{"no-threshold", `{"port": ` + strconv.Itoa(port) + `, "warning_ms": 0}`, "healthy"},
+	}
+
+	for _, e := range tests {
+		res := (&Checker{}).Check(context.Background(), models.Host{IP: "127.0.0.1"}, hostService(e.config))
+		if res.Status != e.expected {
+			t.Errorf("%s: expected %s, but got %s (%s)", e.name, e.expected, res.Status, res.Message)
+		}
+		if res.Metrics["connect_ms"] < 30 {
+			t.Errorf("%s: expected connect_ms of at least 30, but got %f", e.name, res.Metrics["connect_ms"])
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	realDial := dial
+	defer func() { dial = realDial }()
+
+	// a host that never answers: connecting only ends when the check's time is up
+	dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+	res := checkers.Run(context.Background(), models.Host{IP: "127.0.0.1"}, hostService(`{"port": 9, "timeout_seconds": 1}`))
+	elapsed := time.Since(start)
+
+	if res.Status != "problem" || !strings.Contains(res.Message, "timed out after 1s") {
+		t.Errorf("expected problem timed out after 1s, but got %s (%s)", res.Status, res.Message)
+	}
+	if elapsed < time.Second || elapsed > 3*time.Second {
+		t.Errorf("expected the check to stop after 1s, but it took %s", elapsed)
+	}
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/go-chi/chi/v5"
+	"github.com/wtran29/spectre/internal/agentapi"
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/checkers/sslcheck"
+	"github.com/wtran29/spectre/internal/helpers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// maxAgentResultsBytes caps the size of a batch of results posted by an agent
+const maxAgentResultsBytes = 1 << 20
+
+// agentStatuses are the statuses an agent may report for a host service
+var agentStatuses = map[string]bool{
+	"healthy": true,
+	"warning": true,
+	"problem": true,
+	"unknown": true,
+}
+
+type agentContextKey struct{}
+
+// Agents displays the list of remote agents
+func (repo *DBRepo) Agents(w http.ResponseWriter, r *http.Request) {
+	agents, err := repo.DB.AllAgents()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("agents", agents)
+	// a new agent's token is only shown once, straight after it is created
+	vars.Set("new_token", repo.App.Session.PopString(r.Context(), "agent_token"))
+
+	err = helpers.RenderPage(w, r, "agents", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostAgent creates a remote agent and its token
+func (repo *DBRepo) PostAgent(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.Form.Get("name"))
+	if name == "" {
+		repo.App.Session.Put(r.Context(), "error", "Agent name is required")
+		http.Redirect(w, r, "/admin/agents", http.StatusSeeOther)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Println(err)
+		helpers.ServerError(w, r, err)
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	_, err := repo.DB.InsertAgent(models.Agent{
+		Name:      name,
+		TokenHash: hashAgentToken(token),
+		Active:    1,
+	})
+	if err != nil {
+		log.Println(err)
+		helpers.ServerError(w, r, err)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "agent_token", token)
+	repo.App.Session.Put(r.Context(), "flash", "Agent created")
+	http.Redirect(w, r, "/admin/agents", http.StatusSeeOther)
+}
+
+// DeleteAgent deletes a remote agent; the server takes over the checks of its hosts
+func (repo *DBRepo) DeleteAgent(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		log.Println(err)
+	}
+
+	err = repo.DB.DeleteAgent(id)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Could not delete agent")
+		http.Redirect(w, r, "/admin/agents", http.StatusSeeOther)
+		return
+	}
+
+	for _, h := range hosts {
+		if h.AgentID == id {
+			h.AgentID = 0
+			repo.rescheduleHost(h)
+		}
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Agent deleted")
+	http.Redirect(w, r, "/admin/agents", http.StatusSeeOther)
+}
+
+// runsOnServer reports whether the server, rather than a remote agent, checks a host service.
+// Heartbeat services always stay on the server, since that is where the pings arrive.
+func runsOnServer(h models.Host, hs models.HostService) bool {
+	return h.AgentID == 0 || hs.Service.ServiceName == "Heartbeat"
+}
+
+// rescheduleHost adds the active services of a host to, or removes them from, the server's
+// schedule after the host has been assigned to a different agent
+func (repo *DBRepo) rescheduleHost(h models.Host) {
+	for _, hs := range h.HostServices {
+		if hs.Active != 1 || h.Active != 1 {
+			continue
+		}
+		hs.HostName = h.HostName
+		repo.removeFromMonitorMap(hs)
+		if runsOnServer(h, hs) {
+			repo.addToMonitorMap(hs)
+		}
+	}
+}
+
+// hashAgentToken returns the hash of an agent token that is stored in the database
+func hashAgentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AgentAuth authenticates requests from remote agents by the bearer token in the Authorization
+// header, and records that the agent was seen
+func (repo *DBRepo) AgentAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		agent, err := repo.DB.GetAgentByTokenHash(hashAgentToken(token))
+		if err != nil || agent.Active != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		err = repo.DB.UpdateAgentLastSeen(agent.ID)
+		if err != nil {
+			log.Println(err)
+		}
+
+		ctx := context.WithValue(r.Context(), agentContextKey{}, agent)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// agentFromContext returns the agent authenticated by AgentAuth
+func agentFromContext(ctx context.Context) models.Agent {
+	agent, _ := ctx.Value(agentContextKey{}).(models.Agent)
+	return agent
+}
+
+// AgentRegister is called by an agent when it starts
+func (repo *DBRepo) AgentRegister(w http.ResponseWriter, r *http.Request) {
+	agent := agentFromContext(r.Context())
+	log.Println("Agent", agent.Name, "registered from", r.RemoteAddr)
+
+	writeAgentJSON(w, http.StatusOK, agentapi.RegisterResponse{
+		OK:      true,
+		AgentID: agent.ID,
+		Name:    agent.Name,
+	})
+}
+
+// AgentServices returns the host services an agent should check
+func (repo *DBRepo) AgentServices(w http.ResponseWriter, r *http.Request) {
+	agent := agentFromContext(r.Context())
+
+	services, err := repo.DB.GetServicesForAgent(agent.ID)
+	if err != nil {
+		log.Println(err)
+		writeAgentJSON(w, http.StatusInternalServerError, agentapi.ServicesResponse{Message: "could not get services"})
+		return
+	}
+
+	thresholds := sslcheck.DefaultThresholds()
+	resp := agentapi.ServicesResponse{
+		OK:             true,
+		Services:       []agentapi.Assignment{},
+		SSLWarningDays: thresholds.WarningDays,
+		SSLProblemDays: thresholds.ProblemDays,
+	}
+	hosts := make(map[int]models.Host)
+	for _, hs := range services {
+		h, ok := hosts[hs.HostID]
+		if !ok {
+			h, err = repo.DB.GetHostByID(hs.HostID)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			h.HostServices = nil
+			hosts[hs.HostID] = h
+		}
+		resp.Services = append(resp.Services, agentapi.Assignment{Host: h, HostService: hs})
+	}
+
+	writeAgentJSON(w, http.StatusOK, resp)
+}
+
+// AgentResults receives the results of checks run by an agent, and processes them in the same way
+// as the results of scheduled checks run by the server
+func (repo *DBRepo) AgentResults(w http.ResponseWriter, r *http.Request) {
+	agent := agentFromContext(r.Context())
+
+	var req agentapi.ResultsRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAgentResultsBytes)).Decode(&req)
+	if err != nil {
+		writeAgentJSON(w, http.StatusBadRequest, agentapi.ResultsResponse{Message: "invalid results: " + err.Error()})
+		return
+	}
+
+	resp := agentapi.ResultsResponse{OK: true}
+	for _, res := range req.Results {
+		hs, err := repo.DB.GetHostServiceByID(res.HostServiceID)
+		if err != nil || hs.Active != 1 || !agentStatuses[res.Status] {
+			resp.Rejected = append(resp.Rejected, res.HostServiceID)
+			continue
+		}
+
+		h, err := repo.DB.GetHostByID(hs.HostID)
+		if err != nil || h.AgentID != agent.ID || runsOnServer(h, hs) {
+			resp.Rejected = append(resp.Rejected, res.HostServiceID)
+			continue
+		}
+
+		repo.processScheduledResult(h, hs, checkers.Result{
+			Status:       res.Status,
+			Message:      res.Message,
+			Metrics:      res.Metrics,
+			State:        res.State,
+			Notices:      res.Notices,
+			Certificates: res.Certificates,
+		})
+		resp.Accepted++
+	}
+
+	writeAgentJSON(w, http.StatusOK, resp)
+}
+
+func writeAgentJSON(w http.ResponseWriter, status int, v interface{}) {
+	out, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(out)
+}
@@ -73,3 +73,55 @@ func (repo *DBRepo) AllPendingServices(w http.ResponseWriter, r *http.Request) {
 		printTemplateError(w, err)
 	}
 }
+
+// AllUnknownServices lists all services whose checks could not determine a status
+func (repo *DBRepo) AllUnknownServices(w http.ResponseWriter, r *http.Request) {
+	// get all host services (with host info) for status unknown
+	services, err := repo.DB.GetServicesByStatus("unknown")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	vars := make(jet.VarMap)
+	vars.Set("services", services)
+
+	err = helpers.RenderPage(w, r, "unknown", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// AllUnreachableServices lists all services that failed while a host or service they depend on was
+// down
+func (repo *DBRepo) AllUnreachableServices(w http.ResponseWriter, r *http.Request) {
+	// get all host services (with host info) for status unreachable
+	services, err := repo.DB.GetServicesByStatus("unreachable")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	vars := make(jet.VarMap)
+	vars.Set("services", services)
+
+	err = helpers.RenderPage(w, r, "unreachable", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// AllFlappingServices lists all services that are flapping between statuses
+func (repo *DBRepo) AllFlappingServices(w http.ResponseWriter, r *http.Request) {
+	// get all host services (with host info) that are flapping
+	services, err := repo.DB.GetServicesByStatus("flapping")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	vars := make(jet.VarMap)
+	vars.Set("services", services)
+
+	err = helpers.RenderPage(w, r, "flapping", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
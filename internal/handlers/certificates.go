@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/go-chi/chi/v5"
+	"github.com/wtran29/spectre/internal/certificateutils"
+	"github.com/wtran29/spectre/internal/helpers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// calendarTokenPref is the preference holding the secret token in the url of the certificate
+// expiry calendar feed
+const calendarTokenPref = "certificate_calendar_token"
+
+// expiryWindows are the choices for filtering the certificate inventory by expiry
+var expiryWindows = []struct {
+	Value string
+	Label string
+}{
+	{"", "Any time"},
+	{"expired", "Already expired"},
+	{"7", "Within 7 days"},
+	{"30", "Within 30 days"},
+	{"90", "Within 90 days"},
+}
+
+// Certificates displays the certificate inventory: every certificate found by checks, soonest
+// expiring first
+func (repo *DBRepo) Certificates(w http.ResponseWriter, r *http.Request) {
+	filter := certificateFilter(r)
+
+	certs, err := repo.DB.GetCertificates(filter)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	issuers, err := repo.DB.GetCertificateIssuers()
+	if err != nil {
+		log.Println(err)
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("certificates", certs)
+	vars.Set("issuers", issuers)
+	vars.Set("windows", expiryWindows)
+	vars.Set("issuer", filter.Issuer)
+	vars.Set("expires", r.URL.Query().Get("expires"))
+	vars.Set("query", r.URL.RawQuery)
+	vars.Set("calendar_token", repo.App.PreferenceMap[calendarTokenPref])
+
+	err = helpers.RenderPage(w, r, "certificates", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// CertificatesCSV exports the certificate inventory, with the same filters as the page
+func (repo *DBRepo) CertificatesCSV(w http.ResponseWriter, r *http.Request) {
+	certs, err := repo.DB.GetCertificates(certificateFilter(r))
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="certificates.csv"`)
+
+	now := time.Now()
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"Host", "Service", "Source", "Subject", "Issuer", "Serial Number",
+		"Thumbprint", "Names", "Expires", "Days Remaining", "Key Mismatch"})
+	for _, c := range certs {
+		_ = cw.Write([]string{
+			csvSafe(c.HostName),
+			csvSafe(c.ServiceName),
+			csvSafe(c.Source),
+			csvSafe(c.Subject),
+			csvSafe(c.Issuer),
+			c.SerialNumber,
+			c.Thumbprint,
+			csvSafe(c.DNSNames),
+			c.NotAfter.UTC().Format(time.RFC3339),
+			strconv.Itoa(int(c.NotAfter.Sub(now).Hours() / 24)),
+			strconv.FormatBool(c.KeyMismatch),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Println(err)
+	}
+}
+
+// csvSafe stops a value from being read as a formula when the export is opened in a spreadsheet;
+// certificate subjects and issuers are chosen by whoever issued the certificate
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// CertificateCalendar serves an iCalendar feed of certificate expiry dates. Calendar apps can't
+// log in, so the feed is authenticated by the secret token in its url.
+func (repo *DBRepo) CertificateCalendar(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(chi.URLParam(r, "token"), ".ics")
+	expected := repo.App.PreferenceMap[calendarTokenPref]
+	if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	certs, err := repo.DB.GetCertificates(models.CertificateFilter{})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="certificates.ics"`)
+	_, _ = w.Write([]byte(certificateCalendar(certs, time.Now())))
+}
+
+// RegenerateCalendarToken replaces the token in the url of the certificate expiry calendar feed,
+// so that the old url stops working
+func (repo *DBRepo) RegenerateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Println(err)
+		helpers.ServerError(w, r, err)
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	err := repo.DB.SetSystemPref(calendarTokenPref, token)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Could not create calendar url")
+		http.Redirect(w, r, "/admin/certificates", http.StatusSeeOther)
+		return
+	}
+	repo.App.PreferenceMap[calendarTokenPref] = token
+
+	repo.App.Session.Put(r.Context(), "flash", "Calendar url created")
+	http.Redirect(w, r, "/admin/certificates", http.StatusSeeOther)
+}
+
+// certificateFilter reads the inventory filters from the query string
+func certificateFilter(r *http.Request) models.CertificateFilter {
+	filter := models.CertificateFilter{Issuer: r.URL.Query().Get("issuer")}
+
+	switch expires := r.URL.Query().Get("expires"); expires {
+	case "":
+	case "expired":
+		filter.ExpiresBefore = time.Now()
+	default:
+		if days, err := strconv.Atoi(expires); err == nil && days > 0 {
+			filter.ExpiresBefore = time.Now().AddDate(0, 0, days)
+		}
+	}
+
+	return filter
+}
+
+// certificateCalendar returns an iCalendar (RFC 5545) with an all day event on the expiry date of
+// each certificate
+func certificateCalendar(certs []models.Certificate, now time.Time) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICSLine(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Spectre//Certificate Expiry//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:Certificate Expiry")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, c := range certs {
+		day := c.NotAfter.UTC()
+		summary := fmt.Sprintf("Certificate expires: %s", c.Subject)
+		if c.HostName != "" {
+			summary = fmt.Sprintf("%s (%s)", summary, c.HostName)
+		}
+		description := fmt.Sprintf("Issuer: %s\nFound in: %s\nSerial number: %s\nThumbprint: %s\nExpires: %s",
+			c.Issuer, c.Source, c.SerialNumber, c.Thumbprint, day.Format(time.RFC1123))
+
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%d-%s@spectre", c.HostServiceID, strings.ReplaceAll(c.Thumbprint, ":", "")))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeICSText(summary))
+		line("DESCRIPTION:" + escapeICSText(description))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a value of type TEXT
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine breaks a content line longer than 75 octets, without splitting a utf-8 sequence
+func foldICSLine(s string) string {
+	const limit = 75
+
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
+
+// certificatesForInventory converts the certificates found by a check of a host service into
+// inventory entries
+func certificatesForInventory(hs models.HostService, found []certificateutils.CertificateDetails) []models.Certificate {
+	var certs []models.Certificate
+	for _, cd := range found {
+		certs = append(certs, models.Certificate{
+			HostServiceID: hs.ID,
+			Source:        cd.Source,
+			Subject:       cd.SubjectName,
+			Issuer:        cd.IssuerName,
+			SerialNumber:  cd.SerialNumber,
+			Thumbprint:    cd.Thumbprint,
+			DNSNames:      strings.Join(cd.DNSNames, ", "),
+			NotAfter:      cd.NotAfter,
+			KeyMismatch:   cd.KeyMismatch,
+		})
+	}
+	return certs
+}
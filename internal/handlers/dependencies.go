@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// dependencyNode is a host (Service 0) or a host service in the dependency graph
+type dependencyNode struct {
+	Host    int
+	Service int
+}
+
+func childNode(d models.Dependency) dependencyNode {
+	return dependencyNode{Host: d.HostID, Service: d.HostServiceID}
+}
+
+func parentNode(d models.Dependency) dependencyNode {
+	return dependencyNode{Host: d.ParentHostID, Service: d.ParentHostServiceID}
+}
+
+// dependencyLine is a line of a dependency tree on the host page
+type dependencyLine struct {
+	Depth int
+	// DependencyID is set on the lines for dependencies declared by the host or its services,
+	// which can be removed from the host page
+	DependencyID int
+	HostID       int
+	Label        string
+	Status       string
+	// Via names the host, or the service on it, that the line's dependency belongs to
+	Via string
+}
+
+// PostDependency adds a parent dependency to a host, or to one of its services, unless it would
+// create a cycle
+func (repo *DBRepo) PostDependency(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		log.Println(err)
+	}
+
+	var resp jsonResp
+	resp.OK = true
+
+	hostID, _ := strconv.Atoi(r.Form.Get("host_id"))
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = "Could not load hosts"
+	}
+	deps, err := repo.DB.AllDependencies()
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = "Could not load dependencies"
+	}
+
+	if resp.OK {
+		d, err := dependencyFromForm(hostID, r.Form.Get("child"), r.Form.Get("parent"), hosts)
+		if err == nil {
+			err = checkDependency(d, deps, hosts)
+		}
+		if err != nil {
+			resp.OK = false
+			resp.Message = err.Error()
+		} else if _, err := repo.DB.InsertDependency(d); err != nil {
+			log.Println(err)
+			resp.OK = false
+			resp.Message = "Could not save dependency"
+		}
+	}
+	resp.HostID = hostID
+
+	out, _ := json.MarshalIndent(resp, "", "	")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// RemoveDependency removes a dependency
+func (repo *DBRepo) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		log.Println(err)
+	}
+
+	var resp jsonResp
+	resp.OK = true
+
+	id, _ := strconv.Atoi(r.Form.Get("dependency_id"))
+	err = repo.DB.DeleteDependency(id)
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = "Could not remove dependency"
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "	")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// dependencyFromForm builds a dependency of the host being edited from the posted child, which is
+// "host-<id>" for the host itself or "service-<id>" for one of its services, and parent, which
+// names any host or host service the same way
+func dependencyFromForm(hostID int, child, parent string, hosts []models.Host) (models.Dependency, error) {
+	var d models.Dependency
+
+	kind, id, ok := parseTarget(child)
+	if !ok {
+		return d, errors.New("Choose what depends on the parent")
+	}
+	d.HostID = hostID
+	if kind == "service" {
+		d.HostServiceID = id
+	} else if id != hostID {
+		return d, errors.New("Choose this host or one of its services")
+	}
+
+	kind, id, ok = parseTarget(parent)
+	if !ok {
+		return d, errors.New("Choose the parent host or service")
+	}
+	if kind == "host" {
+		d.ParentHostID = id
+	} else {
+		d.ParentHostServiceID = id
+	}
+
+	// check both ends exist, and find the host of a parent service
+	var childFound, parentFound bool
+	for _, h := range hosts {
+		if h.ID == d.HostID && d.HostServiceID == 0 {
+			childFound = true
+		}
+		if h.ID == d.ParentHostID && d.ParentHostServiceID == 0 {
+			parentFound = true
+		}
+		for _, hs := range h.HostServices {
+			if hs.ID == d.HostServiceID && h.ID == d.HostID {
+				childFound = true
+			}
+			if hs.ID == d.ParentHostServiceID {
+				d.ParentHostID = h.ID
+				parentFound = true
+			}
+		}
+	}
+	if !childFound {
+		return d, errors.New("Choose this host or one of its services")
+	}
+	if !parentFound {
+		return d, errors.New("Unknown parent host or service")
+	}
+
+	return d, nil
+}
+
+// checkDependency rejects a new dependency that duplicates an existing one or would create a cycle
+func checkDependency(d models.Dependency, deps []models.Dependency, hosts []models.Host) error {
+	for _, existing := range deps {
+		if childNode(existing) == childNode(d) && parentNode(existing) == parentNode(d) {
+			return errors.New("This dependency already exists")
+		}
+	}
+	if dependencyCycle(append(deps, d), hostServiceIDs(hosts)) {
+		return errors.New("This dependency would create a cycle")
+	}
+	return nil
+}
+
+// hostServiceIDs returns the ids of the services of each host
+func hostServiceIDs(hosts []models.Host) map[int][]int {
+	services := make(map[int][]int)
+	for _, h := range hosts {
+		for _, hs := range h.HostServices {
+			services[h.ID] = append(services[h.ID], hs.ID)
+		}
+	}
+	return services
+}
+
+// dependencyCycle reports whether dependencies form a cycle, in which a service could end up
+// unreachable because of itself. The services of a host inherit the host's dependencies, and a host
+// is down when its services are, so both count as edges of the graph too.
+func dependencyCycle(deps []models.Dependency, services map[int][]int) bool {
+	edges := make(map[dependencyNode][]dependencyNode)
+	for host, ids := range services {
+		for _, id := range ids {
+			edges[dependencyNode{Host: host}] = append(edges[dependencyNode{Host: host}], dependencyNode{Host: host, Service: id})
+		}
+	}
+	for _, d := range deps {
+		child, parent := childNode(d), parentNode(d)
+		edges[child] = append(edges[child], parent)
+		if d.HostServiceID == 0 {
+			for _, id := range services[d.HostID] {
+				n := dependencyNode{Host: d.HostID, Service: id}
+				edges[n] = append(edges[n], parent)
+			}
+		}
+	}
+
+	// depth first search, marking the nodes on the current path
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[dependencyNode]int)
+	var visit func(n dependencyNode) bool
+	visit = func(n dependencyNode) bool {
+		switch state[n] {
+		case onPath:
+			return true
+		case done:
+			return false
+		}
+		state[n] = onPath
+		for _, next := range edges[n] {
+			if visit(next) {
+				return true
+			}
+		}
+		state[n] = done
+		return false
+	}
+
+	for n := range edges {
+		if state[n] == unvisited && visit(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDependencies marks a failing host service unreachable, rather than in problem, when a host
+// or service it depends on is down
+func (repo *DBRepo) applyDependencies(hs models.HostService, res checkers.Result) checkers.Result {
+	if res.Status != "problem" {
+		return res
+	}
+	if parent := repo.downParent(hs); parent != "" {
+		res.Status = "unreachable"
+		res.Message = fmt.Sprintf("%s is down: %s", parent, res.Message)
+	}
+	return res
+}
+
+// downParent returns the name of a parent host or service of a host service that is down, or ""
+// when none is
+func (repo *DBRepo) downParent(hs models.HostService) string {
+	deps, err := repo.DB.AllDependencies()
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
+
+	for _, d := range deps {
+		if d.HostID != hs.HostID || (d.HostServiceID != 0 && d.HostServiceID != hs.ID) {
+			continue
+		}
+
+		if d.ParentHostServiceID > 0 {
+			parent, err := repo.DB.GetHostServiceByID(d.ParentHostServiceID)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if parent.Active == 1 && statusDown(parent.Status) {
+				return dependencyLabel(d.ParentHostName, d.ParentServiceName)
+			}
+			continue
+		}
+
+		parent, err := repo.DB.GetHostByID(d.ParentHostID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if hostDown(parent) {
+			return d.ParentHostName
+		}
+	}
+	return ""
+}
+
+// statusDown reports whether a status means a host service is down
+func statusDown(status string) bool {
+	return status == "problem" || status == "unreachable"
+}
+
+// hostDown reports whether a host is down: every one of its active services that has been checked
+// is down
+func hostDown(h models.Host) bool {
+	down := false
+	for _, hs := range h.HostServices {
+		if hs.Active != 1 || hs.Status == "pending" {
+			continue
+		}
+		if !statusDown(hs.Status) {
+			return false
+		}
+		down = true
+	}
+	return down
+}
+
+// dependencyLabel names a host, or a service on it
+func dependencyLabel(hostName, serviceName string) string {
+	if serviceName == "" {
+		return hostName
+	}
+	return hostName + " / " + serviceName
+}
+
+// dependencyTrees returns the trees of what a host and its services depend on, and of what depends
+// on them
+func dependencyTrees(hostID int, deps []models.Dependency, hosts []models.Host) (parents, children []dependencyLine) {
+	status := make(map[dependencyNode]string)
+	for _, h := range hosts {
+		status[dependencyNode{Host: h.ID}] = "up"
+		if hostDown(h) {
+			status[dependencyNode{Host: h.ID}] = "down"
+		}
+		for _, hs := range h.HostServices {
+			status[dependencyNode{Host: h.ID, Service: hs.ID}] = hs.Status
+		}
+	}
+
+	// parentsOf returns the dependencies of a node, including those a service inherits from its host
+	parentsOf := func(n dependencyNode) []models.Dependency {
+		var found []models.Dependency
+		for _, d := range deps {
+			if childNode(d) == n || (n.Service != 0 && childNode(d) == dependencyNode{Host: n.Host}) {
+				found = append(found, d)
+			}
+		}
+		return found
+	}
+	// childrenOf returns the dependencies on a node, including those on any service of a host
+	childrenOf := func(n dependencyNode) []models.Dependency {
+		var found []models.Dependency
+		for _, d := range deps {
+			if parentNode(d) == n || (n.Service == 0 && d.ParentHostID == n.Host) {
+				found = append(found, d)
+			}
+		}
+		return found
+	}
+
+	var walkUp func(n dependencyNode, depth int, seen map[int]bool)
+	walkUp = func(n dependencyNode, depth int, seen map[int]bool) {
+		for _, d := range parentsOf(n) {
+			if seen[d.ID] {
+				continue
+			}
+			seen[d.ID] = true
+			parents = append(parents, dependencyLine{
+				Depth:  depth,
+				HostID: d.ParentHostID,
+				Label:  dependencyLabel(d.ParentHostName, d.ParentServiceName),
+				Status: status[parentNode(d)],
+				Via:    dependencyLabel(d.HostName, d.ServiceName),
+			})
+			walkUp(parentNode(d), depth+1, seen)
+		}
+	}
+
+	var walkDown func(n dependencyNode, depth int, seen map[int]bool)
+	walkDown = func(n dependencyNode, depth int, seen map[int]bool) {
+		for _, d := range childrenOf(n) {
+			if seen[d.ID] {
+				continue
+			}
+			seen[d.ID] = true
+			children = append(children, dependencyLine{
+				Depth:  depth,
+				HostID: d.HostID,
+				Label:  dependencyLabel(d.HostName, d.ServiceName),
+				Status: status[childNode(d)],
+				Via:    dependencyLabel(d.ParentHostName, d.ParentServiceName),
+			})
+			walkDown(childNode(d), depth+1, seen)
+		}
+	}
+
+	// the host's own dependencies come first, then those of each of its services
+	top := []dependencyNode{{Host: hostID}}
+	for _, h := range hosts {
+		if h.ID == hostID {
+			for _, hs := range h.HostServices {
+				top = append(top, dependencyNode{Host: hostID, Service: hs.ID})
+			}
+		}
+	}
+	seen := make(map[int]bool)
+	for _, n := range top {
+		for _, d := range parentsOf(n) {
+			if childNode(d) != n || seen[d.ID] {
+				continue
+			}
+			seen[d.ID] = true
+			parents = append(parents, dependencyLine{
+				DependencyID: d.ID,
+				HostID:       d.ParentHostID,
+				Label:        dependencyLabel(d.ParentHostName, d.ParentServiceName),
+				Status:       status[parentNode(d)],
+				Via:          dependencyLabel(d.HostName, d.ServiceName),
+			})
+			walkUp(parentNode(d), 1, seen)
+		}
+	}
+	walkDown(dependencyNode{Host: hostID}, 0, make(map[int]bool))
+
+	return parents, children
+}
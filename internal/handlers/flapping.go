@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+// flapWindow is the number of recent statuses of a host service its flap score is computed over
+const flapWindow = 21
+
+// default flap scores, in percent, at which a host service starts and stops flapping
+const (
+	defaultFlapStart = 50
+	defaultFlapStop  = 25
+)
+
+// parseFlapThresholds reads the flap_start_percent and flap_stop_percent preferences. Values that
+// are missing or out of range fall back to the defaults.
+func parseFlapThresholds(start, stop string) (float64, float64) {
+	startPercent, err := strconv.ParseFloat(start, 64)
+	if err != nil || startPercent <= 0 || startPercent > 100 {
+		startPercent = defaultFlapStart
+	}
+	stopPercent, err := strconv.ParseFloat(stop, 64)
+	if err != nil || stopPercent <= 0 || stopPercent > startPercent {
+		stopPercent = defaultFlapStop
+		if stopPercent > startPercent {
+			stopPercent = startPercent
+		}
+	}
+	return startPercent, stopPercent
+}
+
+// appendStatus adds a status to the comma separated history of a host service, keeping the most
+// recent flapWindow statuses
+func appendStatus(history, status string) string {
+	var statuses []string
+	if history != "" {
+		statuses = strings.Split(history, ",")
+	}
+	statuses = append(statuses, status)
+	if len(statuses) > flapWindow {
+		statuses = statuses[len(statuses)-flapWindow:]
+	}
+	return strings.Join(statuses, ",")
+}
+
+// flapScore returns the percentage of the checks in the window, oldest status first, where the
+// status changed. Recent changes weigh more than old ones, from 0.8 for the oldest possible change
+// to 1.2 for the newest, so that a service which has settled down stops flapping sooner. A history
+// shorter than the window is scored as if the missing statuses had not changed.
+func flapScore(statuses []string) float64 {
+	offset := flapWindow - len(statuses)
+	changes := 0.0
+	for i := 1; i < len(statuses); i++ {
+		if statuses[i] != statuses[i-1] {
+			changes += 0.8 + 0.4*float64(offset+i-1)/float64(flapWindow-2)
+		}
+	}
+	return 100 * changes / float64(flapWindow-1)
+}
+
+// isFlapping decides whether a host service is flapping, given whether it was flapping and its flap
+// score. Starting takes a higher score than stopping, so that a score hovering around one threshold
+// does not turn flapping on and off.
+func isFlapping(wasFlapping bool, score, start, stop float64) bool {
+	if wasFlapping {
+		return score >= stop
+	}
+	return score >= start
+}
+
+// updateFlapping adds a new status to the history of a host service and works out whether the
+// service is now flapping, notifying when it starts or stops. It returns the host service with its
+// history, flap score and flapping updated.
+func (repo *DBRepo) updateFlapping(h models.Host, hs models.HostService, newStatus string) models.HostService {
+	if newStatus == "pending" {
+		return hs
+	}
+	wasFlapping := hs.Flapping == 1
+
+	history := appendStatus(hs.StatusHistory, newStatus)
+	score := math.Round(flapScore(strings.Split(history, ","))*10) / 10
+	start, stop := parseFlapThresholds(repo.App.PreferenceMap["flap_start_percent"], repo.App.PreferenceMap["flap_stop_percent"])
+	flapping := isFlapping(wasFlapping, score, start, stop)
+
+	flag := 0
+	if flapping {
+		flag = 1
+	}
+	err := repo.DB.UpdateHostServiceFlapping(hs.ID, history, score, flag)
+	if err != nil {
+		log.Println(err)
+		return hs
+	}
+
+	hs.StatusHistory, hs.FlapScore, hs.Flapping = history, score, flag
+	if flapping != wasFlapping {
+		repo.flappingChanged(h, hs, newStatus)
+	}
+	return hs
+}
+
+// flappingChanged records, broadcasts and notifies the start or end of flapping for a host service
+func (repo *DBRepo) flappingChanged(h models.Host, hs models.HostService, status string) {
+	eventType, msg := "flapping-stopped", fmt.Sprintf("%s on %s stopped flapping and reports %s", hs.Service.ServiceName, h.HostName, status)
+	if hs.Flapping == 1 {
+		eventType, msg = "flapping-started", fmt.Sprintf("%s on %s is flapping (flap score %.0f%%); notifications are paused until it settles", hs.Service.ServiceName, h.HostName, hs.FlapScore)
+	}
+
+	maintenance := repo.inMaintenance(hs)
+	err := repo.DB.InsertEvent(models.Event{
+		EventType:     eventType,
+		HostServiceID: hs.ID,
+		HostID:        h.ID,
+		ServiceName:   hs.Service.ServiceName,
+		HostName:      h.HostName,
+		Message:       msg,
+		Maintenance:   maintenanceFlag(maintenance),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	data := make(map[string]string)
+	data["host_id"] = strconv.Itoa(hs.HostID)
+	data["host_service_id"] = strconv.Itoa(hs.ID)
+	data["host_name"] = h.HostName
+	data["service_name"] = hs.Service.ServiceName
+	data["flapping"] = strconv.Itoa(hs.Flapping)
+	data["flap_score"] = strconv.FormatFloat(hs.FlapScore, 'f', 0, 64)
+	data["message"] = msg
+	repo.broadcastMessage("public-channel", "host-service-flapping", data)
+	repo.pushStatusCounts()
+
+	subject := fmt.Sprintf("FLAPPING STOPPED: service %s on %s", hs.Service.ServiceName, h.HostName)
+	if hs.Flapping == 1 {
+		subject = fmt.Sprintf("FLAPPING: service %s on %s", hs.Service.ServiceName, h.HostName)
+	}
+	m := notifiers.Message{
+		EventType:     eventType,
+		HostID:        h.ID,
+		HostServiceID: hs.ID,
+		HostName:      h.HostName,
+		ServiceName:   hs.Service.ServiceName,
+		OldStatus:     hs.Status,
+		Status:        status,
+		Subject:       subject,
+		Detail:        msg,
+		Text:          msg,
+		HTML:          template.HTML(fmt.Sprintf("<p>%s</p>", template.HTMLEscapeString(msg))),
+		Time:          time.Now(),
+	}
+
+	// in maintenance nothing is announced, but flapping that settles off problem still resolves
+	// any incident opened for the service
+	if maintenance {
+		repo.resolveIncidents(m)
+		return
+	}
+	repo.notify(m)
+}
@@ -7,13 +7,19 @@ import (
 	"net/http"
 	"runtime/debug"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/go-chi/chi/v5"
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/checkers/execcheck"
+	"github.com/wtran29/spectre/internal/checkers/sslcheck"
 	"github.com/wtran29/spectre/internal/config"
 	"github.com/wtran29/spectre/internal/driver"
 	"github.com/wtran29/spectre/internal/helpers"
 	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
 	"github.com/wtran29/spectre/internal/repository"
 	"github.com/wtran29/spectre/internal/repository/dbrepo"
 )
@@ -44,17 +50,20 @@ func NewPostgresqlHandlers(db *driver.DB, a *config.AppConfig) *DBRepo {
 
 // AdminDashboard displays the dashboard
 func (repo *DBRepo) AdminDashboard(w http.ResponseWriter, r *http.Request) {
-	pending, healthy, warning, problem, err := repo.DB.GetAllServiceStatusCounts()
+	counts, err := repo.DB.GetAllServiceStatusCounts()
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
 	vars := make(jet.VarMap)
-	vars.Set("no_healthy", healthy)
-	vars.Set("no_warning", warning)
-	vars.Set("no_problem", problem)
-	vars.Set("no_pending", pending)
+	vars.Set("no_healthy", counts.Healthy)
+	vars.Set("no_warning", counts.Warning)
+	vars.Set("no_problem", counts.Problem)
+	vars.Set("no_pending", counts.Pending)
+	vars.Set("no_unknown", counts.Unknown)
+	vars.Set("no_unreachable", counts.Unreachable)
+	vars.Set("no_flapping", counts.Flapping)
 
 	allHosts, err := repo.DB.AllHosts()
 	if err != nil {
@@ -63,6 +72,14 @@ func (repo *DBRepo) AdminDashboard(w http.ResponseWriter, r *http.Request) {
 	}
 	vars.Set("hosts", allHosts)
 
+	windows, err := repo.DB.AllMaintenanceWindows()
+	if err != nil {
+		log.Println(err)
+	}
+	maintenance := servicesInMaintenance(allHosts, windows, time.Now())
+	vars.Set("maintenance", maintenance)
+	vars.Set("no_maintenance", len(maintenance))
+
 	err = helpers.RenderPage(w, r, "dashboard", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
@@ -87,7 +104,22 @@ func (repo *DBRepo) Events(w http.ResponseWriter, r *http.Request) {
 
 // Settings displays the settings page
 func (repo *DBRepo) Settings(w http.ResponseWriter, r *http.Request) {
-	err := helpers.RenderPage(w, r, "settings", nil, nil)
+	channels, err := repo.DB.AllNotificationChannels()
+	if err != nil {
+		log.Println(err)
+	}
+
+	labels := make(map[int]string)
+	for _, ch := range channels {
+		labels[ch.ID] = notifiers.Label(ch.ChannelType)
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("channels", channels)
+	vars.Set("labels", labels)
+	vars.Set("webhook_urls", settingsWebhookURLs(channels))
+
+	err = helpers.RenderPage(w, r, "settings", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
@@ -98,38 +130,77 @@ func (repo *DBRepo) PostSettings(w http.ResponseWriter, r *http.Request) {
 	prefMap := make(map[string]string)
 
 	prefMap["site_url"] = r.Form.Get("site_url")
-	prefMap["notify_name"] = r.Form.Get("notify_name")
-	prefMap["notify_email"] = r.Form.Get("notify_email")
 	prefMap["smtp_server"] = r.Form.Get("smtp_server")
 	prefMap["smtp_port"] = r.Form.Get("smtp_port")
 	prefMap["smtp_user"] = r.Form.Get("smtp_user")
 	prefMap["smtp_password"] = r.Form.Get("smtp_password")
-	prefMap["sms_enabled"] = r.Form.Get("sms_enabled")
 	prefMap["sms_provider"] = r.Form.Get("sms_provider")
 	prefMap["twilio_phone_number"] = r.Form.Get("twilio_phone_number")
 	prefMap["twilio_sid"] = r.Form.Get("twilio_sid")
 	prefMap["twilio_auth_token"] = r.Form.Get("twilio_auth_token")
 	prefMap["smtp_from_email"] = r.Form.Get("smtp_from_email")
 	prefMap["smtp_from_name"] = r.Form.Get("smtp_from_name")
-	prefMap["notify_via_sms"] = r.Form.Get("notify_via_sms")
-	prefMap["notify_via_email"] = r.Form.Get("notify_via_email")
-	prefMap["sms_notify_number"] = r.Form.Get("sms_notify_number")
+	prefMap["exec_allowlist"] = r.Form.Get("exec_allowlist")
+	prefMap["ssl_warning_days"] = r.Form.Get("ssl_warning_days")
+	prefMap["ssl_problem_days"] = r.Form.Get("ssl_problem_days")
+	prefMap["flap_start_percent"] = r.Form.Get("flap_start_percent")
+	prefMap["flap_stop_percent"] = r.Form.Get("flap_stop_percent")
+
+	thresholds := sslcheck.ParseThresholds(prefMap["ssl_warning_days"], prefMap["ssl_problem_days"])
+	if thresholds.WarningDays <= 0 || thresholds.ProblemDays <= 0 || thresholds.ProblemDays > thresholds.WarningDays {
+		app.Session.Put(r.Context(), "error", "Certificate expiry days must be positive, with the problem days no more than the warning days")
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
 
-	if r.Form.Get("sms_enabled") == "0" {
-		prefMap["notify_via_sms"] = "0"
+	flapStart, startErr := strconv.ParseFloat(prefMap["flap_start_percent"], 64)
+	flapStop, stopErr := strconv.ParseFloat(prefMap["flap_stop_percent"], 64)
+	if startErr != nil || stopErr != nil || flapStart < 1 || flapStart > 100 || flapStop < 1 || flapStop > 100 || flapStop >= flapStart {
+		app.Session.Put(r.Context(), "error", "Flap thresholds must be between 1 and 100 percent, with the stop threshold below the start threshold")
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
 	}
 
-	err := repo.DB.InsertOrUpdateSitePreferences(prefMap)
+	channels, err := repo.DB.AllNotificationChannels()
 	if err != nil {
 		log.Println(err)
 		ClientError(w, r, http.StatusBadRequest)
 		return
 	}
+	webhookChanges, err := settingsWebhookChanges(channels, r.Form)
+	if err != nil {
+		app.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
+
+	err = repo.DB.InsertOrUpdateSitePreferences(prefMap)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	for _, ch := range webhookChanges {
+		if ch.ID > 0 {
+			err = repo.DB.UpdateNotificationChannel(ch)
+		} else {
+			_, err = repo.DB.InsertNotificationChannel(ch)
+		}
+		if err != nil {
+			log.Println(err)
+			app.Session.Put(r.Context(), "error", fmt.Sprintf("Could not save the %s webhook", notifiers.Label(ch.ChannelType)))
+			http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+			return
+		}
+	}
 
 	// update app config
 	for k, v := range prefMap {
 		app.PreferenceMap[k] = v
 	}
+	execcheck.SetAllowlist(execcheck.ParseAllowlist(prefMap["exec_allowlist"]))
+	sslcheck.SetDefaultThresholds(thresholds)
 
 	app.Session.Put(r.Context(), "flash", "Changes saved")
 
@@ -177,8 +248,29 @@ func (repo *DBRepo) Host(w http.ResponseWriter, r *http.Request) {
 		h = host
 	}
 
+	agents, err := repo.DB.AllAgents()
+	if err != nil {
+		log.Println(err)
+	}
+
 	vars := make(jet.VarMap)
 	vars.Set("host", h)
+	vars.Set("agents", agents)
+
+	if id > 0 {
+		hosts, err := repo.DB.AllHosts()
+		if err != nil {
+			log.Println(err)
+		}
+		deps, err := repo.DB.AllDependencies()
+		if err != nil {
+			log.Println(err)
+		}
+		parents, children := dependencyTrees(id, deps, hosts)
+		vars.Set("all_hosts", hosts)
+		vars.Set("depends_on", parents)
+		vars.Set("depended_on_by", children)
+	}
 
 	err = helpers.RenderPage(w, r, "host", vars, nil)
 	if err != nil {
@@ -214,6 +306,8 @@ func (repo *DBRepo) PostHost(w http.ResponseWriter, r *http.Request) {
 	h.OS = r.Form.Get("os")
 	active, _ := strconv.Atoi(r.Form.Get("active"))
 	h.Active = active
+	oldAgentID := h.AgentID
+	h.AgentID, _ = strconv.Atoi(r.Form.Get("agent_id"))
 
 	if id > 0 {
 		err := repo.DB.UpdateHost(h)
@@ -221,6 +315,9 @@ func (repo *DBRepo) PostHost(w http.ResponseWriter, r *http.Request) {
 			log.Println(err)
 			return
 		}
+		if h.AgentID != oldAgentID {
+			repo.rescheduleHost(h)
+		}
 	} else {
 		newID, err := repo.DB.InsertHost(h)
 		if err != nil {
@@ -404,12 +501,19 @@ func (repo *DBRepo) ToggleServiceForHost(w http.ResponseWriter, r *http.Request)
 	hs, _ := repo.DB.GetHostServiceByHostIdServiceId(hostID, serviceID)
 	h, _ := repo.DB.GetHostByID(hostID)
 
+	// heartbeat services need a ping url before a job can report in
+	if active == 1 && hs.HeartbeatToken == "" && hs.Service.ServiceName == "Heartbeat" {
+		_, _ = repo.setHeartbeatToken(hs)
+	}
+
 	// add or remove host service from schedule
 	if active == 1 {
 		// add to schedule
 		repo.pushScheduleChangedEvent(hs, "pending")
-		repo.pushStatusChangedEvent(h, hs, "pending")
-		repo.addToMonitorMap(hs)
+		repo.pushStatusChangedEvent(h, hs, "pending", nil)
+		if runsOnServer(h, hs) {
+			repo.addToMonitorMap(hs)
+		}
 	} else {
 		// remove schedule
 		repo.removeFromMonitorMap(hs)
@@ -420,6 +524,68 @@ func (repo *DBRepo) ToggleServiceForHost(w http.ResponseWriter, r *http.Request)
 	w.Write(out)
 }
 
+// PostHostServiceConfig saves the check configuration (a json object) for a host service
+func (repo *DBRepo) PostHostServiceConfig(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		log.Println(err)
+	}
+
+	var resp jsonResp
+	resp.OK = true
+
+	hostServiceID, _ := strconv.Atoi(r.Form.Get("host_service_id"))
+	config := strings.TrimSpace(r.Form.Get("config"))
+	if config == "" {
+		config = "{}"
+	}
+
+	hs, err := repo.DB.GetHostServiceByID(hostServiceID)
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = "Could not find host service"
+	}
+
+	var obj map[string]interface{}
+	if resp.OK {
+		hs.Config = config
+		// a literal null unmarshals without error, so check for an object as well
+		if err := json.Unmarshal([]byte(config), &obj); err != nil || obj == nil {
+			resp.OK = false
+			resp.Message = "Configuration must be a json object"
+		} else if err := checkers.Validate(hs); err != nil {
+			resp.OK = false
+			resp.Message = err.Error()
+		}
+	}
+
+	if resp.OK {
+		err = repo.DB.UpdateHostServiceConfig(hostServiceID, config)
+		if err != nil {
+			log.Println(err)
+			resp.OK = false
+			resp.Message = "Could not save configuration"
+		}
+	}
+
+	// settings such as retry_seconds change the schedule, so replace the cron entry
+	if resp.OK && hs.Active == 1 {
+		h, err := repo.DB.GetHostByID(hs.HostID)
+		if err != nil {
+			log.Println(err)
+		} else if runsOnServer(h, hs) {
+			repo.removeFromMonitorMap(hs)
+			repo.addToMonitorMap(hs)
+		}
+	}
+	resp.HostServiceID = hostServiceID
+
+	out, _ := json.MarshalIndent(resp, "", "	")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
 func (repo *DBRepo) SetSystemPref(w http.ResponseWriter, r *http.Request) {
 
 	prefName := r.PostForm.Get("pref_name")
@@ -457,6 +623,8 @@ func (repo *DBRepo) ToggleMonitoring(w http.ResponseWriter, r *http.Request) {
 		// stop monitoring
 		log.Println("Turning monitoring off")
 		repo.App.PreferenceMap["monitoring_live"] = "0"
+		// stop any checks that are still running
+		cancelMonitoringContext()
 		// remove all items in map from schedule
 		for _, x := range repo.App.MonitorMap {
 			repo.App.Scheduler.Remove(x)
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wtran29/spectre/internal/models"
 )
 
 var loginTests = []struct {
@@ -104,3 +110,593 @@ func TestDBRepo_PusherAuth(t *testing.T) {
 		t.Error("empty json response")
 	}
 }
+
+var heartbeatTests = []struct {
+	name                 string
+	url                  string
+	token                string
+	expectedResponseCode int
+}{
+	{"valid-token", "/ping/valid-token", "valid-token", http.StatusOK},
+	{"unknown-token", "/ping/unknown", "unknown", http.StatusNotFound},
+}
+
+func TestDBRepo_Heartbeat(t *testing.T) {
+	for _, e := range heartbeatTests {
+		req, _ := http.NewRequest("GET", e.url, nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("token", e.token)
+		req = req.WithContext(context.WithValue(getCtx(req), chi.RouteCtxKey, chiCtx))
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.Heartbeat)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != e.expectedResponseCode {
+			t.Errorf("%s, expected %d, but got %d", e.name, e.expectedResponseCode, rr.Code)
+		}
+	}
+}
+
+var hostServiceConfigTests = []struct {
+	name       string
+	config     string
+	expectedOK bool
+}{
+	{"empty", "", true},
+	{"object", `{"retry_seconds": 30}`, true},
+	{"null", "null", false},
+	{"array", "[1, 2]", false},
+	{"string", `"config"`, false},
+	{"invalid", "{", false},
+}
+
+func TestDBRepo_PostHostServiceConfig(t *testing.T) {
+	for _, e := range hostServiceConfigTests {
+		postedData := url.Values{
+			"host_service_id": {"1"},
+			"config":          {e.config},
+		}
+		req, _ := http.NewRequest("POST", "/admin/host/ajax/service-config", strings.NewReader(postedData.Encode()))
+		req = req.WithContext(getCtx(req))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.PostHostServiceConfig)
+		handler.ServeHTTP(rr, req)
+
+		var resp jsonResp
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s: could not parse response: %s", e.name, err)
+			continue
+		}
+		if resp.OK != e.expectedOK {
+			t.Errorf("%s: expected ok %t, but got %t (%s)", e.name, e.expectedOK, resp.OK, resp.Message)
+		}
+	}
+}
+
+var agentAuthTests = []struct {
+	name                 string
+	authorization        string
+	expectedResponseCode int
+}{
+	{"no-token", "", http.StatusUnauthorized},
+	{"wrong-scheme", "Basic agent-token", http.StatusUnauthorized},
+	{"unknown-token", "Bearer not-a-token", http.StatusUnauthorized},
+	{"valid-token", "Bearer agent-token", http.StatusOK},
+}
+
+func TestDBRepo_AgentAuth(t *testing.T) {
+	for _, e := range agentAuthTests {
+		req, _ := http.NewRequest("POST", "/agent/api/register", nil)
+		if e.authorization != "" {
+			req.Header.Set("Authorization", e.authorization)
+		}
+		rr := httptest.NewRecorder()
+
+		handler := Repo.AgentAuth(http.HandlerFunc(Repo.AgentRegister))
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != e.expectedResponseCode {
+			t.Errorf("%s, expected %d, but got %d", e.name, e.expectedResponseCode, rr.Code)
+		}
+	}
+}
+
+var calendarTests = []struct {
+	name                 string
+	token                string
+	expectedResponseCode int
+}{
+	{"valid-token", "calendar-token.ics", http.StatusOK},
+	{"valid-token-no-extension", "calendar-token", http.StatusOK},
+	{"wrong-token", "not-the-token.ics", http.StatusNotFound},
+	{"empty-token", "", http.StatusNotFound},
+}
+
+func TestDBRepo_CertificateCalendar(t *testing.T) {
+	app.PreferenceMap[calendarTokenPref] = "calendar-token"
+	defer delete(app.PreferenceMap, calendarTokenPref)
+
+	for _, e := range calendarTests {
+		req, _ := http.NewRequest("GET", "/calendar/certificates/"+e.token, nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("token", e.token)
+		req = req.WithContext(context.WithValue(getCtx(req), chi.RouteCtxKey, chiCtx))
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(Repo.CertificateCalendar)
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != e.expectedResponseCode {
+			t.Errorf("%s, expected %d, but got %d", e.name, e.expectedResponseCode, rr.Code)
+		}
+		if rr.Code != http.StatusOK {
+			continue
+		}
+
+		body := rr.Body.String()
+		for _, expected := range []string{
+			"BEGIN:VCALENDAR\r\n",
+			"DTSTART;VALUE=DATE:20300102\r\n",
+			`SUMMARY:Certificate expires: www.example.com (web\, primary)`,
+			"END:VCALENDAR\r\n",
+		} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("%s, expected feed to contain %q, but got %q", e.name, expected, body)
+			}
+		}
+		for _, l := range strings.Split(body, "\r\n") {
+			if len(l) > 75 {
+				t.Errorf("%s, expected lines of at most 75 octets, but got %q", e.name, l)
+			}
+		}
+	}
+}
+
+func TestDBRepo_CertificatesCSV(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/admin/certificates/csv?issuer=Example+CA", nil)
+	req = req.WithContext(getCtx(req))
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(Repo.CertificatesCSV)
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %d, but got %d", http.StatusOK, rr.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and one certificate, but got %q", rr.Body.String())
+	}
+	if !strings.HasPrefix(lines[1], `"web, primary",SSL Certificate,www.example.com:443,www.example.com,Example CA,0A-1B,`) {
+		t.Errorf("expected the certificate row, but got %q", lines[1])
+	}
+}
+
+var nextAttemptTests = []struct {
+	name             string
+	status           string
+	attempts         int
+	newStatus        string
+	retries          int
+	expectedAttempts int
+}{
+	{"unchanged", "healthy", 0, "healthy", 3, 0},
+	{"no-retries", "healthy", 0, "problem", 1, 0},
+	{"first-failure", "healthy", 0, "problem", 3, 1},
+	{"second-failure", "healthy", 1, "problem", 3, 2},
+	{"confirmed", "healthy", 2, "problem", 3, 0},
+	{"blip-over", "healthy", 1, "healthy", 3, 0},
+	{"first-recovery", "problem", 0, "healthy", 2, 1},
+	{"recovery-confirmed", "problem", 1, "healthy", 2, 0},
+	{"from-pending", "pending", 0, "problem", 3, 0},
+}
+
+func TestNextAttempt(t *testing.T) {
+	for _, e := range nextAttemptTests {
+		hs := models.HostService{Status: e.status, Attempts: e.attempts}
+		attempts := nextAttempt(hs, e.newStatus, e.retries)
+		if attempts != e.expectedAttempts {
+			t.Errorf("%s, expected %d attempts, but got %d", e.name, e.expectedAttempts, attempts)
+		}
+	}
+}
+
+func TestFlapScore(t *testing.T) {
+	alternating := make([]string, flapWindow)
+	for i := range alternating {
+		alternating[i] = "healthy"
+		if i%2 == 1 {
+			alternating[i] = "problem"
+		}
+	}
+	settled := append([]string{}, alternating[:11]...)
+	for len(settled) < flapWindow {
+		settled = append(settled, "healthy")
+	}
+	recent := make([]string, flapWindow)
+	for i := range recent {
+		recent[i] = "healthy"
+		if i >= 11 && i%2 == 1 {
+			recent[i] = "problem"
+		}
+	}
+
+	var tests = []struct {
+		name     string
+		statuses []string
+		min      float64
+		max      float64
+	}{
+		{"empty", nil, 0, 0},
+		{"steady", strings.Split("healthy,healthy,healthy,healthy", ","), 0, 0},
+		{"one-change", strings.Split("healthy,healthy,problem", ","), 5, 7},
+		{"short-history", strings.Split("healthy,problem,healthy,problem", ","), 15, 20},
+		{"alternating", alternating, 100, 100},
+		{"settled", settled, 40, 50},
+		{"recent", recent, 50, 60},
+	}
+
+	for _, e := range tests {
+		score := flapScore(e.statuses)
+		if score < e.min-0.001 || score > e.max+0.001 {
+			t.Errorf("%s: expected a score from %.0f to %.0f, but got %.2f", e.name, e.min, e.max, score)
+		}
+	}
+}
+
+func TestIsFlapping(t *testing.T) {
+	var tests = []struct {
+		name        string
+		wasFlapping bool
+		score       float64
+		expected    bool
+	}{
+		{"quiet", false, 10, false},
+		{"starts", false, 50, true},
+		{"between-not-flapping", false, 40, false},
+		{"between-flapping", true, 40, true},
+		{"stops", true, 20, false},
+	}
+
+	for _, e := range tests {
+		if got := isFlapping(e.wasFlapping, e.score, 50, 25); got != e.expected {
+			t.Errorf("%s: expected %t, but got %t", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestAppendStatus(t *testing.T) {
+	history := ""
+	for i := 0; i < flapWindow+5; i++ {
+		history = appendStatus(history, strconv.Itoa(i))
+	}
+	statuses := strings.Split(history, ",")
+	if len(statuses) != flapWindow {
+		t.Errorf("expected %d statuses, but got %d", flapWindow, len(statuses))
+	}
+	if statuses[len(statuses)-1] != strconv.Itoa(flapWindow+4) {
+		t.Errorf("expected the newest status last, but got %s", history)
+	}
+}
+
+func TestParseFlapThresholds(t *testing.T) {
+	var tests = []struct {
+		name          string
+		start         string
+		stop          string
+		expectedStart float64
+		expectedStop  float64
+	}{
+		{"set", "60", "30", 60, 30},
+		{"missing", "", "", defaultFlapStart, defaultFlapStop},
+		{"too-high", "150", "30", defaultFlapStart, 30},
+		{"stop-above-start", "40", "45", 40, defaultFlapStop},
+		{"low-start", "20", "", 20, 20},
+	}
+
+	for _, e := range tests {
+		start, stop := parseFlapThresholds(e.start, e.stop)
+		if start != e.expectedStart || stop != e.expectedStop {
+			t.Errorf("%s: expected %.0f/%.0f, but got %.0f/%.0f", e.name, e.expectedStart, e.expectedStop, start, stop)
+		}
+	}
+}
+
+func TestMaintenanceActive(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+	// Sunday 17 May 2026, 03:00 in London (BST)
+	sunday := time.Date(2026, 5, 17, 3, 0, 0, 0, london)
+
+	var tests = []struct {
+		name     string
+		window   models.MaintenanceWindow
+		now      time.Time
+		expected bool
+	}{
+		{"once-during", models.MaintenanceWindow{StartsAt: sunday.Add(-time.Hour), EndsAt: sunday.Add(time.Hour)}, sunday, true},
+		{"once-after", models.MaintenanceWindow{StartsAt: sunday.Add(-2 * time.Hour), EndsAt: sunday.Add(-time.Hour)}, sunday, false},
+		{"once-at-end", models.MaintenanceWindow{StartsAt: sunday.Add(-time.Hour), EndsAt: sunday}, sunday, false},
+		{"weekly-during", models.MaintenanceWindow{Recurrence: "weekly", Weekday: 0, StartTime: "02:00", EndTime: "04:00", Timezone: "Europe/London"}, sunday, true},
+		{"weekly-other-day", models.MaintenanceWindow{Recurrence: "weekly", Weekday: 6, StartTime: "02:00", EndTime: "04:00", Timezone: "Europe/London"}, sunday, false},
+		{"weekly-other-timezone", models.MaintenanceWindow{Recurrence: "weekly", Weekday: 0, StartTime: "02:00", EndTime: "04:00", Timezone: "UTC"}, sunday, true},
+		{"weekly-utc-too-early", models.MaintenanceWindow{Recurrence: "weekly", Weekday: 0, StartTime: "02:30", EndTime: "04:00", Timezone: "UTC"}, sunday, false},
+		{"weekly-past-midnight", models.MaintenanceWindow{Recurrence: "weekly", Weekday: 6, StartTime: "23:00", EndTime: "04:00", Timezone: "Europe/London"}, sunday, true},
+		{"daily-during", models.MaintenanceWindow{Recurrence: "daily", StartTime: "02:59", EndTime: "03:01", Timezone: "Europe/London"}, sunday, true},
+		{"daily-outside", models.MaintenanceWindow{Recurrence: "daily", StartTime: "04:00", EndTime: "05:00", Timezone: "Europe/London"}, sunday, false},
+	}
+
+	for _, e := range tests {
+		if got := maintenanceActive(e.window, e.now); got != e.expected {
+			t.Errorf("%s: expected %t, but got %t", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestMaintenanceWindowFromForm(t *testing.T) {
+	var tests = []struct {
+		name        string
+		form        url.Values
+		expectError bool
+	}{
+		{"once", url.Values{"target": {"host-1"}, "starts_at": {"2026-05-17T02:00"}, "ends_at": {"2026-05-17T04:00"}}, false},
+		{"weekly", url.Values{"target": {"service-3"}, "recurrence": {"weekly"}, "weekday": {"0"}, "start_time": {"02:00"}, "end_time": {"04:00"}, "timezone": {"UTC"}}, false},
+		{"daily-past-midnight", url.Values{"target": {"host-1"}, "recurrence": {"daily"}, "start_time": {"23:00"}, "end_time": {"01:00"}}, false},
+		{"no-target", url.Values{"starts_at": {"2026-05-17T02:00"}, "ends_at": {"2026-05-17T04:00"}}, true},
+		{"bad-target", url.Values{"target": {"agent-1"}, "starts_at": {"2026-05-17T02:00"}, "ends_at": {"2026-05-17T04:00"}}, true},
+		{"ends-before-start", url.Values{"target": {"host-1"}, "starts_at": {"2026-05-17T04:00"}, "ends_at": {"2026-05-17T02:00"}}, true},
+		{"bad-timezone", url.Values{"target": {"host-1"}, "timezone": {"Mars/Olympus"}, "starts_at": {"2026-05-17T02:00"}, "ends_at": {"2026-05-17T04:00"}}, true},
+		{"bad-weekday", url.Values{"target": {"host-1"}, "recurrence": {"weekly"}, "weekday": {"7"}, "start_time": {"02:00"}, "end_time": {"04:00"}}, true},
+		{"same-times", url.Values{"target": {"host-1"}, "recurrence": {"daily"}, "start_time": {"02:00"}, "end_time": {"02:00"}}, true},
+		{"bad-recurrence", url.Values{"target": {"host-1"}, "recurrence": {"monthly"}}, true},
+	}
+
+	for _, e := range tests {
+		_, err := maintenanceWindowFromForm(e.form)
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	// hosts 1 (services 11, 12), 2 (service 21) and 3 (service 31)
+	services := map[int][]int{1: {11, 12}, 2: {21}, 3: {31}}
+	hostOn := func(host, parent int) models.Dependency {
+		return models.Dependency{HostID: host, ParentHostID: parent}
+	}
+	serviceOn := func(host, service, parentHost, parentService int) models.Dependency {
+		return models.Dependency{HostID: host, HostServiceID: service, ParentHostID: parentHost, ParentHostServiceID: parentService}
+	}
+
+	var tests = []struct {
+		name     string
+		deps     []models.Dependency
+		expected bool
+	}{
+		{"none", nil, false},
+		{"chain", []models.Dependency{hostOn(1, 2), hostOn(2, 3)}, false},
+		{"self", []models.Dependency{hostOn(1, 1)}, true},
+		{"two-hosts", []models.Dependency{hostOn(1, 2), hostOn(2, 1)}, true},
+		{"three-hosts", []models.Dependency{hostOn(1, 2), hostOn(2, 3), hostOn(3, 1)}, true},
+		{"service-on-same-host", []models.Dependency{serviceOn(1, 12, 1, 11)}, false},
+		{"services-on-each-other", []models.Dependency{serviceOn(1, 12, 1, 11), serviceOn(1, 11, 1, 12)}, true},
+		{"service-on-own-host", []models.Dependency{serviceOn(1, 11, 1, 0)}, true},
+		{"service-through-host", []models.Dependency{serviceOn(1, 11, 2, 21), hostOn(2, 1)}, true},
+		{"host-through-service", []models.Dependency{hostOn(1, 2), serviceOn(2, 21, 3, 31)}, false},
+	}
+
+	for _, e := range tests {
+		if got := dependencyCycle(e.deps, services); got != e.expected {
+			t.Errorf("%s: expected %t, but got %t", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestHostDown(t *testing.T) {
+	var tests = []struct {
+		name     string
+		services []models.HostService
+		expected bool
+	}{
+		{"no-services", nil, false},
+		{"all-problem", []models.HostService{{Active: 1, Status: "problem"}, {Active: 1, Status: "unreachable"}}, true},
+		{"one-healthy", []models.HostService{{Active: 1, Status: "problem"}, {Active: 1, Status: "healthy"}}, false},
+		{"inactive-and-pending-ignored", []models.HostService{{Active: 1, Status: "problem"}, {Active: 0, Status: "healthy"}, {Active: 1, Status: "pending"}}, true},
+		{"only-pending", []models.HostService{{Active: 1, Status: "pending"}}, false},
+	}
+
+	for _, e := range tests {
+		if got := hostDown(models.Host{HostServices: e.services}); got != e.expected {
+			t.Errorf("%s: expected %t, but got %t", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestDependencyFromForm(t *testing.T) {
+	hosts := []models.Host{
+		{ID: 1, HostName: "web", HostServices: []models.HostService{{ID: 11}}},
+		{ID: 2, HostName: "router", HostServices: []models.HostService{{ID: 21}}},
+	}
+
+	var tests = []struct {
+		name           string
+		child          string
+		parent         string
+		expectError    bool
+		expectedParent int
+	}{
+		{"host-on-host", "host-1", "host-2", false, 2},
+		{"service-on-service", "service-11", "service-21", false, 2},
+		{"other-host", "host-2", "host-1", true, 0},
+		{"other-hosts-service", "service-21", "host-2", true, 0},
+		{"unknown-parent", "host-1", "service-99", true, 0},
+		{"no-parent", "host-1", "", true, 0},
+	}
+
+	for _, e := range tests {
+		d, err := dependencyFromForm(1, e.child, e.parent, hosts)
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+			continue
+		}
+		if err == nil && d.ParentHostID != e.expectedParent {
+			t.Errorf("%s: expected parent host %d, but got %d", e.name, e.expectedParent, d.ParentHostID)
+		}
+	}
+}
+
+func TestDependencyTrees(t *testing.T) {
+	hosts := []models.Host{
+		{ID: 1, HostName: "web", HostServices: []models.HostService{{ID: 11, Active: 1, Status: "problem"}}},
+		{ID: 2, HostName: "switch", HostServices: []models.HostService{{ID: 21, Active: 1, Status: "healthy"}}},
+		{ID: 3, HostName: "router", HostServices: []models.HostService{{ID: 31, Active: 1, Status: "problem"}}},
+		{ID: 4, HostName: "db"},
+	}
+	deps := []models.Dependency{
+		{ID: 1, HostID: 1, HostName: "web", ParentHostID: 2, ParentHostName: "switch"},
+		{ID: 2, HostID: 2, HostName: "switch", ParentHostID: 3, ParentHostName: "router"},
+		{ID: 3, HostID: 4, HostName: "db", ParentHostID: 1, ParentHostServiceID: 11, ParentHostName: "web", ParentServiceName: "HTTP"},
+	}
+
+	parents, children := dependencyTrees(1, deps, hosts)
+	if len(parents) != 2 || parents[0].Label != "switch" || parents[0].DependencyID != 1 ||
+		parents[1].Label != "router" || parents[1].Depth != 1 || parents[1].DependencyID != 0 || parents[1].Status != "down" {
+		t.Errorf("expected web to depend on switch, and through it on router which is down, but got %+v", parents)
+	}
+	if len(children) != 1 || children[0].Label != "db" || children[0].Via != "web / HTTP" {
+		t.Errorf("expected db to depend on web / HTTP, but got %+v", children)
+	}
+}
+
+func TestNotificationChannelFromForm(t *testing.T) {
+	var tests = []struct {
+		name           string
+		form           url.Values
+		expectError    bool
+		expectedConfig string
+		expectedOn     int
+	}{
+		{"email", url.Values{"name": {"Ops"}, "channel_type": {"email"}, "enabled": {"1"}, "email_to_address": {" ops@example.com "}, "sms_to": {"+15555550123"}}, false, `{"to_address":"ops@example.com","to_name":""}`, 1},
+		{"sms-disabled", url.Values{"name": {"Pager"}, "channel_type": {"sms"}, "sms_to": {"+15555550123"}}, false, `{"to":"+15555550123"}`, 0},
+		{"no-name", url.Values{"channel_type": {"email"}}, true, "", 0},
+		{"unknown-type", url.Values{"name": {"Ops"}, "channel_type": {"telegraph"}}, true, "", 0},
+	}
+
+	for _, e := range tests {
+		ch, err := notificationChannelFromForm(e.form)
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+		if err != nil {
+			continue
+		}
+		if ch.Config != e.expectedConfig {
+			t.Errorf("%s: expected config %s, but got %s", e.name, e.expectedConfig, ch.Config)
+		}
+		if ch.Enabled != e.expectedOn {
+			t.Errorf("%s: expected enabled %d, but got %d", e.name, e.expectedOn, ch.Enabled)
+		}
+	}
+}
+
+func TestSettingsWebhookChanges(t *testing.T) {
+	slack := models.NotificationChannel{ID: 4, Name: "Ops Slack", ChannelType: "slack", Enabled: 0,
+		Config: `{"webhook_url":"https://hooks.slack.com/services/old"}`}
+
+	var tests = []struct {
+		name          string
+		channels      []models.NotificationChannel
+		form          url.Values
+		expectError   bool
+		expectedCount int
+		expectedID    int
+		expectedOn    int
+	}{
+		{"nothing-set", nil, url.Values{}, false, 0, 0, 0},
+		{"new-teams", nil, url.Values{"teams_webhook_url": {" https://example.webhook.office.com/x "}}, false, 1, 0, 1},
+		{"unchanged-stays-off", []models.NotificationChannel{slack}, url.Values{"slack_webhook_url": {"https://hooks.slack.com/services/old"}}, false, 0, 0, 0},
+		{"changed-turns-on", []models.NotificationChannel{slack}, url.Values{"slack_webhook_url": {"https://hooks.slack.com/services/new"}}, false, 1, 4, 1},
+		{"cleared-turns-off", []models.NotificationChannel{slack}, url.Values{}, false, 1, 4, 0},
+		{"invalid-url", nil, url.Values{"slack_webhook_url": {"not a url"}}, true, 0, 0, 0},
+	}
+
+	for _, e := range tests {
+		changes, err := settingsWebhookChanges(e.channels, e.form)
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+		if len(changes) != e.expectedCount {
+			t.Errorf("%s: expected %d changes, but got %d", e.name, e.expectedCount, len(changes))
+			continue
+		}
+		if len(changes) == 1 {
+			if changes[0].ID != e.expectedID {
+				t.Errorf("%s: expected channel %d, but got %d", e.name, e.expectedID, changes[0].ID)
+			}
+			if changes[0].Enabled != e.expectedOn {
+				t.Errorf("%s: expected enabled %d, but got %d", e.name, e.expectedOn, changes[0].Enabled)
+			}
+		}
+	}
+}
+
+func TestStatusMessage(t *testing.T) {
+	h := models.Host{ID: 1, HostName: "web"}
+	hs := models.HostService{ID: 7, HostID: 1, HostName: "web", Status: "healthy", Service: models.Services{ServiceName: "HTTP"}}
+
+	var tests = []struct {
+		name            string
+		status          string
+		expectedSubject string
+		expectedText    string
+	}{
+		{"problem", "problem", "PROBLEM: service HTTP on web", "Service HTTP on web reports a problem: timed out"},
+		{"warning", "warning", "WARNING: service HTTP on web", "Service HTTP on web reports a warning: timed out"},
+		{"unknown", "unknown", "UNKNOWN: service HTTP on web", "Service HTTP on web reports unknown status: timed out"},
+		{"healthy", "healthy", "HEALTHY: service HTTP on web", "Service HTTP on web is healthy"},
+	}
+
+	for _, e := range tests {
+		m := statusMessage(h, hs, e.status, "timed out")
+		if m.Subject != e.expectedSubject {
+			t.Errorf("%s: expected subject %q, but got %q", e.name, e.expectedSubject, m.Subject)
+		}
+		if m.Text != e.expectedText {
+			t.Errorf("%s: expected text %q, but got %q", e.name, e.expectedText, m.Text)
+		}
+		if m.OldStatus != "healthy" || m.Status != e.status || m.HostServiceID != 7 {
+			t.Errorf("%s: expected healthy to %s for host service 7, but got %s to %s for %d", e.name, e.status, m.OldStatus, m.Status, m.HostServiceID)
+		}
+	}
+
+	m := statusMessage(h, hs, "problem", "<b>bad</b>")
+	if strings.Contains(string(m.HTML), "<b>") {
+		t.Errorf("expected check message to be escaped, but got %s", m.HTML)
+	}
+}
+
+func TestHostURL(t *testing.T) {
+	var tests = []struct {
+		name     string
+		siteURL  string
+		hostID   int
+		expected string
+	}{
+		{"site-url", "https://spectre.example.com", 4, "https://spectre.example.com/admin/host/4"},
+		{"trailing-slash", "https://spectre.example.com/", 4, "https://spectre.example.com/admin/host/4"},
+		{"no-site-url", "", 4, ""},
+		{"no-host", "https://spectre.example.com", 0, ""},
+	}
+
+	for _, e := range tests {
+		if got := hostURL(e.siteURL, e.hostID); got != e.expected {
+			t.Errorf("%s: expected %q, but got %q", e.name, e.expected, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// maxHeartbeatMessage caps the message a job can send with a ping
+const maxHeartbeatMessage = 250
+
+// Heartbeat receives a ping from a job monitored by a heartbeat host service. The job calls
+// /ping/{token} when it succeeds and /ping/{token}/fail when it fails, optionally with a msg
+// query parameter. The ping is only recorded; the scheduler evaluates it on the host service's
+// next check, so an unauthenticated caller cannot spend retry attempts or trigger notifications.
+func (repo *DBRepo) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	hs, err := repo.DB.GetHostServiceByHeartbeatToken(token)
+	if err != nil || hs.ID == 0 {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	ok := 1
+	if chi.URLParam(r, "result") == "fail" {
+		ok = 0
+	}
+
+	msg := r.URL.Query().Get("msg")
+	if len(msg) > maxHeartbeatMessage {
+		msg = msg[:maxHeartbeatMessage]
+	}
+
+	err = repo.DB.RecordHeartbeat(hs.ID, ok, msg)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte("OK"))
+}
+
+// RegenerateHeartbeatToken gives a host service a new secret ping url, invalidating the old one
+func (repo *DBRepo) RegenerateHeartbeatToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		log.Println(err)
+	}
+
+	var resp jsonResp
+	resp.OK = true
+
+	hostServiceID, _ := strconv.Atoi(r.Form.Get("host_service_id"))
+	hs, err := repo.DB.GetHostServiceByID(hostServiceID)
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = "Could not find host service"
+	} else {
+		token, err := repo.setHeartbeatToken(hs)
+		if err != nil {
+			resp.OK = false
+			resp.Message = "Could not generate ping url"
+		} else {
+			resp.Message = token
+		}
+	}
+	resp.HostServiceID = hostServiceID
+
+	out, _ := json.MarshalIndent(resp, "", "	")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// setHeartbeatToken generates and saves a new ping token for a host service
+func (repo *DBRepo) setHeartbeatToken(hs models.HostService) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Println(err)
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	err := repo.DB.UpdateHeartbeatToken(hs.ID, token)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	return token, nil
+}
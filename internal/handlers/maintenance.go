@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/go-chi/chi/v5"
+	"github.com/wtran29/spectre/internal/helpers"
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// maintenanceRecurrences are the ways a maintenance window can repeat
+var maintenanceRecurrences = []struct {
+	Value string
+	Label string
+}{
+	{"", "Once"},
+	{"daily", "Every day"},
+	{"weekly", "Every week"},
+}
+
+// Maintenance displays the maintenance windows, and a form to schedule a new one
+func (repo *DBRepo) Maintenance(w http.ResponseWriter, r *http.Request) {
+	windows, err := repo.DB.AllMaintenanceWindows()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	active := make(map[int]bool)
+	schedules := make(map[int]string)
+	for _, mw := range windows {
+		active[mw.ID] = maintenanceActive(mw, now)
+		schedules[mw.ID] = describeMaintenance(mw)
+	}
+
+	var weekdays []string
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		weekdays = append(weekdays, d.String())
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("windows", windows)
+	vars.Set("active", active)
+	vars.Set("schedules", schedules)
+	vars.Set("hosts", hosts)
+	vars.Set("recurrences", maintenanceRecurrences)
+	vars.Set("weekdays", weekdays)
+
+	err = helpers.RenderPage(w, r, "maintenance", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostMaintenance schedules a maintenance window for a host or one of its services
+func (repo *DBRepo) PostMaintenance(w http.ResponseWriter, r *http.Request) {
+	mw, err := maintenanceWindowFromForm(r.Form)
+	if err != nil {
+		repo.App.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+		return
+	}
+
+	if mw.HostServiceID > 0 {
+		hs, err := repo.DB.GetHostServiceByID(mw.HostServiceID)
+		if err != nil {
+			log.Println(err)
+			repo.App.Session.Put(r.Context(), "error", "Unknown host service")
+			http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+			return
+		}
+		mw.HostID = hs.HostID
+	}
+
+	_, err = repo.DB.InsertMaintenanceWindow(mw)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Could not schedule maintenance")
+		http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Maintenance scheduled")
+	http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+}
+
+// DeleteMaintenance deletes a maintenance window
+func (repo *DBRepo) DeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := repo.DB.DeleteMaintenanceWindow(id)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Could not delete maintenance window")
+		http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Maintenance window deleted")
+	http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+}
+
+// maintenanceWindowFromForm reads and validates a maintenance window from the posted form. The
+// target is "host-<id>" for every service on a host, or "service-<id>" for a single host service,
+// whose host is left for the caller to look up.
+func maintenanceWindowFromForm(form url.Values) (models.MaintenanceWindow, error) {
+	mw := models.MaintenanceWindow{
+		Description: strings.TrimSpace(form.Get("description")),
+		Recurrence:  form.Get("recurrence"),
+		Timezone:    strings.TrimSpace(form.Get("timezone")),
+	}
+
+	kind, id, ok := parseTarget(form.Get("target"))
+	switch {
+	case !ok:
+		return mw, errors.New("Choose a host or service")
+	case kind == "host":
+		mw.HostID = id
+	default:
+		mw.HostServiceID = id
+	}
+
+	if mw.Timezone == "" {
+		mw.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(mw.Timezone)
+	if err != nil {
+		return mw, fmt.Errorf("Unknown timezone %q", mw.Timezone)
+	}
+
+	switch mw.Recurrence {
+	case "":
+		mw.StartsAt, err = time.ParseInLocation("2006-01-02T15:04", form.Get("starts_at"), loc)
+		if err != nil {
+			return mw, errors.New("Enter when the maintenance starts")
+		}
+		mw.EndsAt, err = time.ParseInLocation("2006-01-02T15:04", form.Get("ends_at"), loc)
+		if err != nil {
+			return mw, errors.New("Enter when the maintenance ends")
+		}
+		if !mw.EndsAt.After(mw.StartsAt) {
+			return mw, errors.New("Maintenance must end after it starts")
+		}
+		mw.StartsAt, mw.EndsAt = mw.StartsAt.UTC(), mw.EndsAt.UTC()
+	case "daily", "weekly":
+		start, err := time.Parse("15:04", form.Get("start_time"))
+		if err != nil {
+			return mw, errors.New("Enter the time the maintenance starts")
+		}
+		end, err := time.Parse("15:04", form.Get("end_time"))
+		if err != nil {
+			return mw, errors.New("Enter the time the maintenance ends")
+		}
+		if start.Equal(end) {
+			return mw, errors.New("Maintenance must end at a different time than it starts")
+		}
+		mw.StartTime, mw.EndTime = start.Format("15:04"), end.Format("15:04")
+		if mw.Recurrence == "weekly" {
+			mw.Weekday, err = strconv.Atoi(form.Get("weekday"))
+			if err != nil || mw.Weekday < 0 || mw.Weekday > 6 {
+				return mw, errors.New("Choose the day of the week")
+			}
+		}
+	default:
+		return mw, errors.New("Choose how often the maintenance happens")
+	}
+
+	return mw, nil
+}
+
+// parseTarget reads a host or host service chosen in a form, given as "host-<id>" or
+// "service-<id>"
+func parseTarget(s string) (kind string, id int, ok bool) {
+	kind, n, _ := strings.Cut(s, "-")
+	id, err := strconv.Atoi(n)
+	if err != nil || id <= 0 || (kind != "host" && kind != "service") {
+		return "", 0, false
+	}
+	return kind, id, true
+}
+
+// maintenanceActive reports whether a maintenance window covers the time now
+func maintenanceActive(mw models.MaintenanceWindow, now time.Time) bool {
+	if mw.Recurrence == "" {
+		return !now.Before(mw.StartsAt) && now.Before(mw.EndsAt)
+	}
+
+	loc, err := time.LoadLocation(mw.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err := time.Parse("15:04", mw.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", mw.EndTime)
+	if err != nil {
+		return false
+	}
+	length := end.Sub(start)
+	if length <= 0 {
+		length += 24 * time.Hour
+	}
+
+	// a window covering now started today or, when it runs past midnight, yesterday
+	local := now.In(loc)
+	for _, day := range []time.Time{local, local.AddDate(0, 0, -1)} {
+		if mw.Recurrence == "weekly" && int(day.Weekday()) != mw.Weekday {
+			continue
+		}
+		from := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		if !now.Before(from) && now.Before(from.Add(length)) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeMaintenance describes when a maintenance window happens
+func describeMaintenance(mw models.MaintenanceWindow) string {
+	switch mw.Recurrence {
+	case "daily":
+		return fmt.Sprintf("Every day %s–%s (%s)", mw.StartTime, mw.EndTime, mw.Timezone)
+	case "weekly":
+		return fmt.Sprintf("Every %s %s–%s (%s)", time.Weekday(mw.Weekday), mw.StartTime, mw.EndTime, mw.Timezone)
+	}
+
+	loc, err := time.LoadLocation(mw.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return fmt.Sprintf("%s – %s (%s)", mw.StartsAt.In(loc).Format("2006-01-02 15:04"),
+		mw.EndsAt.In(loc).Format("2006-01-02 15:04"), mw.Timezone)
+}
+
+// inMaintenance reports whether a maintenance window for a host service, or for its host, is
+// running now
+func (repo *DBRepo) inMaintenance(hs models.HostService) bool {
+	windows, err := repo.DB.GetMaintenanceWindowsForHost(hs.HostID)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	now := time.Now()
+	for _, mw := range windows {
+		if (mw.HostServiceID == 0 || mw.HostServiceID == hs.ID) && maintenanceActive(mw, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenanceFlag returns the value of the maintenance column of an event
+func maintenanceFlag(inMaintenance bool) int {
+	if inMaintenance {
+		return 1
+	}
+	return 0
+}
+
+// servicesInMaintenance returns the ids of the host services of hosts that are covered by a
+// maintenance window now
+func servicesInMaintenance(hosts []models.Host, windows []models.MaintenanceWindow, now time.Time) map[int]bool {
+	inMaintenance := make(map[int]bool)
+	for _, mw := range windows {
+		if !maintenanceActive(mw, now) {
+			continue
+		}
+		for _, h := range hosts {
+			if h.ID != mw.HostID {
+				continue
+			}
+			for _, hs := range h.HostServices {
+				if mw.HostServiceID == 0 || mw.HostServiceID == hs.ID {
+					inMaintenance[hs.ID] = true
+				}
+			}
+		}
+	}
+	return inMaintenance
+}
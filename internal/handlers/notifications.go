@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/go-chi/chi/v5"
+	"github.com/wtran29/spectre/internal/helpers"
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+	_ "github.com/wtran29/spectre/internal/notifiers/emailnotifier"     // registers email
+	_ "github.com/wtran29/spectre/internal/notifiers/opsgenienotifier"  // registers opsgenie
+	_ "github.com/wtran29/spectre/internal/notifiers/pagerdutynotifier" // registers pagerduty
+	_ "github.com/wtran29/spectre/internal/notifiers/slacknotifier"     // registers slack
+	_ "github.com/wtran29/spectre/internal/notifiers/smsnotifier"       // registers sms
+	_ "github.com/wtran29/spectre/internal/notifiers/teamsnotifier"     // registers teams
+	"github.com/wtran29/spectre/internal/notifiers/webhooknotifier"
+)
+
+// webhookDeliveriesShown is the number of recent deliveries shown for a webhook channel
+const webhookDeliveriesShown = 50
+
+// Notifications displays the notification channels, and a form to add or edit one
+func (repo *DBRepo) Notifications(w http.ResponseWriter, r *http.Request) {
+	channels, err := repo.DB.AllNotificationChannels()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// the channel being edited, if any, and its settings
+	var editing models.NotificationChannel
+	settings := make(map[string]string)
+	if id, _ := strconv.Atoi(r.URL.Query().Get("edit")); id > 0 {
+		for _, ch := range channels {
+			if ch.ID == id {
+				editing = ch
+			}
+		}
+		_ = notifiers.DecodeConfig(editing, &settings)
+	}
+
+	labels := make(map[int]string)
+	for _, ch := range channels {
+		labels[ch.ID] = notifiers.Label(ch.ChannelType)
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("channels", channels)
+	vars.Set("labels", labels)
+	vars.Set("types", notifiers.Types())
+	vars.Set("editing", editing)
+	vars.Set("settings", settings)
+
+	err = helpers.RenderPage(w, r, "notifications", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostNotificationChannel adds a notification channel, or saves changes to one
+func (repo *DBRepo) PostNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	ch, err := notificationChannelFromForm(r.Form)
+	if err == nil {
+		err = notifiers.Validate(ch)
+	}
+	if err != nil {
+		repo.App.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+		return
+	}
+
+	if ch.ID > 0 {
+		err = repo.DB.UpdateNotificationChannel(ch)
+	} else {
+		_, err = repo.DB.InsertNotificationChannel(ch)
+	}
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Could not save notification channel")
+		http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Notification channel saved")
+	http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+}
+
+// DeleteNotificationChannel deletes a notification channel
+func (repo *DBRepo) DeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := repo.DB.DeleteNotificationChannel(id)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Could not delete notification channel")
+		http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Notification channel deleted")
+	http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+}
+
+// TestNotificationChannel sends a test message to a notification channel, whether or not it is
+// enabled, and reports whether it was sent
+func (repo *DBRepo) TestNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	ch, err := repo.DB.GetNotificationChannelByID(id)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Unknown notification channel")
+		http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+		return
+	}
+
+	text := fmt.Sprintf("This is a test notification from spectre for %s", ch.Name)
+	msg := notifiers.Message{
+		EventType: "test",
+		Subject:   "Test notification",
+		Detail:    text,
+		Text:      text,
+		HTML:      template.HTML(fmt.Sprintf("<p>%s</p>", template.HTMLEscapeString(text))),
+		Time:      time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), notifiers.Timeout(ch))
+	defer cancel()
+	err = notifiers.Send(ctx, ch, msg)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("Could not send to %s: %s", ch.Name, err))
+		http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Test notification sent to %s", ch.Name))
+	http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+}
+
+// WebhookDeliveries displays the recent deliveries to a webhook channel
+func (repo *DBRepo) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	ch, err := repo.DB.GetNotificationChannelByID(id)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusNotFound)
+		return
+	}
+
+	deliveries, err := repo.DB.GetWebhookDeliveries(ch.ID, webhookDeliveriesShown)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("channel", ch)
+	vars.Set("deliveries", deliveries)
+
+	err = helpers.RenderPage(w, r, "webhook-deliveries", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// ReplayWebhookDelivery posts the payload of a delivery to its webhook channel again
+func (repo *DBRepo) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	d, err := repo.DB.GetWebhookDeliveryByID(id)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Unknown delivery")
+		http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+		return
+	}
+
+	redirect := fmt.Sprintf("/admin/notifications/deliveries/%d", d.NotificationChannelID)
+	ch, err := repo.DB.GetNotificationChannelByID(d.NotificationChannelID)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", "Unknown notification channel")
+		http.Redirect(w, r, "/admin/notifications", http.StatusSeeOther)
+		return
+	}
+
+	// a replay is a single try, each of which has its own timeout
+	err = webhooknotifier.Replay(r.Context(), ch, d)
+	if err != nil {
+		log.Println(err)
+		repo.App.Session.Put(r.Context(), "error", fmt.Sprintf("Replay failed: %s", err))
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Delivery replayed")
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// notificationChannelFromForm reads a notification channel from the posted form. The settings of
+// a channel are posted as "<type>_<field>", for each field of its type, and stored as JSON.
+func notificationChannelFromForm(form url.Values) (models.NotificationChannel, error) {
+	ch := models.NotificationChannel{
+		Name:        strings.TrimSpace(form.Get("name")),
+		ChannelType: form.Get("channel_type"),
+	}
+	ch.ID, _ = strconv.Atoi(form.Get("id"))
+	if form.Get("enabled") == "1" {
+		ch.Enabled = 1
+	}
+
+	if ch.Name == "" {
+		return ch, errors.New("Enter a name for the channel")
+	}
+
+	var fields []notifiers.Field
+	found := false
+	for _, t := range notifiers.Types() {
+		if t.Name == ch.ChannelType {
+			fields, found = t.Fields, true
+		}
+	}
+	if !found {
+		return ch, errors.New("Choose the type of channel")
+	}
+
+	settings := make(map[string]string)
+	for _, f := range fields {
+		settings[f.Name] = strings.TrimSpace(form.Get(ch.ChannelType + "_" + f.Name))
+	}
+	cfg, err := json.Marshal(settings)
+	if err != nil {
+		return ch, err
+	}
+	ch.Config = string(cfg)
+
+	return ch, nil
+}
+
+// settingsWebhookTypes are the chat channel types whose incoming webhook url is set on the
+// settings page, posted as "<type>_webhook_url"
+var settingsWebhookTypes = []string{"slack", "teams"}
+
+// settingsWebhookURLs returns the webhook url of the first channel of each settings webhook type
+func settingsWebhookURLs(channels []models.NotificationChannel) map[string]string {
+	urls := make(map[string]string)
+	for _, channelType := range settingsWebhookTypes {
+		ch := settingsWebhookChannel(channels, channelType)
+		var cfg map[string]interface{}
+		if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+			log.Println(err)
+			continue
+		}
+		if u, ok := cfg["webhook_url"].(string); ok {
+			urls[channelType] = u
+		}
+	}
+	return urls
+}
+
+// settingsWebhookChannel returns the first channel of a type, or a new enabled one if none exists
+func settingsWebhookChannel(channels []models.NotificationChannel, channelType string) models.NotificationChannel {
+	for _, ch := range channels {
+		if ch.ChannelType == channelType {
+			return ch
+		}
+	}
+	return models.NotificationChannel{Name: notifiers.Label(channelType), ChannelType: channelType, Enabled: 1}
+}
+
+// settingsWebhookChanges returns the chat channels to save for the webhook urls posted with the
+// settings. A new url is validated and turns its channel on; clearing a url turns it off. Channels
+// whose url is unchanged are left alone, so one switched off on the notifications page stays off.
+func settingsWebhookChanges(channels []models.NotificationChannel, form url.Values) ([]models.NotificationChannel, error) {
+	var changes []models.NotificationChannel
+	for _, channelType := range settingsWebhookTypes {
+		ch := settingsWebhookChannel(channels, channelType)
+		webhookURL := strings.TrimSpace(form.Get(channelType + "_webhook_url"))
+
+		cfg := make(map[string]interface{})
+		if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+			return nil, err
+		}
+		current, _ := cfg["webhook_url"].(string)
+		if webhookURL == current || (webhookURL == "" && ch.ID == 0) {
+			continue
+		}
+
+		cfg["webhook_url"] = webhookURL
+		out, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		ch.Config = string(out)
+
+		if webhookURL == "" {
+			ch.Enabled = 0
+		} else {
+			ch.Enabled = 1
+			if err := notifiers.Validate(ch); err != nil {
+				return nil, fmt.Errorf("%s: %w", notifiers.Label(channelType), err)
+			}
+		}
+		changes = append(changes, ch)
+	}
+	return changes, nil
+}
+
+// statusMessage builds the notification for a host service whose status changed
+func statusMessage(h models.Host, hs models.HostService, newStatus, msg string) notifiers.Message {
+	m := notifiers.Message{
+		EventType:     newStatus,
+		HostID:        h.ID,
+		HostServiceID: hs.ID,
+		HostName:      hs.HostName,
+		ServiceName:   hs.Service.ServiceName,
+		OldStatus:     hs.Status,
+		Status:        newStatus,
+		Detail:        msg,
+		Time:          time.Now(),
+	}
+
+	var reported string
+	switch newStatus {
+	case "healthy":
+		reported = "reported healthy status"
+		m.Text = fmt.Sprintf("Service %s on %s is healthy", m.ServiceName, m.HostName)
+	case "problem":
+		reported = "reported problem"
+		m.Text = fmt.Sprintf("Service %s on %s reports a problem: %s", m.ServiceName, m.HostName, msg)
+	case "warning":
+		reported = "reported warning"
+		m.Text = fmt.Sprintf("Service %s on %s reports a warning: %s", m.ServiceName, m.HostName, msg)
+	default:
+		reported = fmt.Sprintf("reported %s status", newStatus)
+		m.Text = fmt.Sprintf("Service %s on %s reports %s status: %s", m.ServiceName, m.HostName, newStatus, msg)
+	}
+
+	m.Subject = fmt.Sprintf("%s: service %s on %s", strings.ToUpper(newStatus), m.ServiceName, m.HostName)
+	m.HTML = template.HTML(fmt.Sprintf(`<p>Service %s on %s %s</p>
+				<p><strong>Message received:</strong> %s</p>`, template.HTMLEscapeString(m.ServiceName),
+		template.HTMLEscapeString(m.HostName), reported, template.HTMLEscapeString(msg)))
+	return m
+}
+
+// hostURL returns the address of a host's page, or "" when the site url is not set
+func hostURL(siteURL string, hostID int) string {
+	if siteURL == "" || hostID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/admin/host/%d", strings.TrimRight(siteURL, "/"), hostID)
+}
+
+// notify sends a message to every enabled notification channel. Channels are sent to in the
+// background, so a slow channel holds up neither the check nor the other channels.
+func (repo *DBRepo) notify(msg notifiers.Message) {
+	repo.sendToChannels(msg, false)
+}
+
+// resolveIncidents passes a status change that is not announced to the enabled channels that track
+// incidents, so that an incident opened before maintenance, flapping or an unreachable parent is
+// resolved once the service leaves problem
+func (repo *DBRepo) resolveIncidents(msg notifiers.Message) {
+	if !msg.ResolvesIncident() {
+		return
+	}
+	repo.sendToChannels(msg, true)
+}
+
+// sendToChannels sends a message in the background to every enabled channel, or, for
+// incidentsOnly, resolves incidents on the channels that track them
+func (repo *DBRepo) sendToChannels(msg notifiers.Message, incidentsOnly bool) {
+	if msg.URL == "" {
+		msg.URL = hostURL(repo.App.PreferenceMap["site_url"], msg.HostID)
+	}
+
+	channels, err := repo.DB.GetEnabledNotificationChannels()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	send := notifiers.Send
+	if incidentsOnly {
+		send = notifiers.ResolveIncident
+	}
+
+	for _, ch := range channels {
+		if incidentsOnly && !notifiers.TracksIncidents(ch.ChannelType) {
+			continue
+		}
+
+		go func(ch models.NotificationChannel) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifiers.Timeout(ch))
+			defer cancel()
+
+			err := send(ctx, ch, msg)
+			if err != nil {
+				log.Println(fmt.Sprintf("Error notifying %s:", ch.Name), err)
+			}
+		}(ch)
+	}
+}
@@ -1,27 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/wtran29/spectre/internal/certificateutils"
-	"github.com/wtran29/spectre/internal/channeldata"
-	"github.com/wtran29/spectre/internal/helpers"
+	"github.com/wtran29/spectre/internal/checkers"
+	_ "github.com/wtran29/spectre/internal/checkers/certfilecheck" // registers Certificate Files
+	_ "github.com/wtran29/spectre/internal/checkers/dnscheck"      // registers DNS
+	_ "github.com/wtran29/spectre/internal/checkers/execcheck"     // registers Exec
+	_ "github.com/wtran29/spectre/internal/checkers/heartbeat"     // registers Heartbeat
+	_ "github.com/wtran29/spectre/internal/checkers/httpcheck"     // registers HTTP and HTTPS
+	_ "github.com/wtran29/spectre/internal/checkers/pingcheck"     // registers Ping
+	_ "github.com/wtran29/spectre/internal/checkers/sslcheck"      // registers SSL Certificate
+	_ "github.com/wtran29/spectre/internal/checkers/tcpcheck"      // registers TCP
 	"github.com/wtran29/spectre/internal/models"
-	"github.com/wtran29/spectre/internal/sms"
-)
-
-const (
-	HTTP           = 1
-	HTTPS          = 2
-	SSLCertificate = 3
 )
 
 // jsonResp is the JSON response that is sent back to client
@@ -52,12 +51,92 @@ func (repo *DBRepo) ScheduledCheck(hostServiceID int) {
 		return
 	}
 	// tests the service
-	newStatus, msg := repo.testServiceForHost(h, hs)
+	ctx := monitoringContext()
+	res := checkers.Run(ctx, h, hs)
+	if ctx.Err() != nil {
+		log.Println("Check for", hostServiceID, "cancelled:", ctx.Err())
+		return
+	}
+	repo.processScheduledResult(h, hs, res)
+}
 
-	if newStatus != hs.Status {
-		repo.updateHostServiceStatusCount(h, hs, newStatus, msg)
+// processScheduledResult handles the result of a scheduled check of a host service, whether the
+// check ran here or on a remote agent. A new status only replaces the current one once the
+// number of consecutive checks set by the host service's retries have reported it; until then the
+// attempts are recorded, without events or notifications, and the service is checked at its
+// retry interval.
+func (repo *DBRepo) processScheduledResult(h models.Host, hs models.HostService, res checkers.Result) {
+	res = repo.applyDependencies(hs, res)
+	retries, _ := checkers.Retries(hs)
+	attempts := nextAttempt(hs, res.Status, retries)
+
+	if attempts > 0 {
+		repo.recordCheckData(h, hs, res)
+		repo.setAttempts(h, hs, attempts, res.Status, res.Message)
+		repo.pushScheduleChangedEvent(hs, hs.Status)
+		return
+	}
+	if hs.Attempts > 0 {
+		// the change was confirmed, or the status went back to the current one
+		repo.setAttempts(h, hs, 0, "", res.Message)
+	}
+
+	repo.recordCheckResult(h, hs, res)
+
+	if res.Status != hs.Status {
+		repo.updateHostServiceStatusCount(h, hs, res.Status, res.Message)
+	}
+}
+
+// nextAttempt returns how many consecutive checks, counting this one, have reported a status
+// other than the current status of a host service. Zero means there is nothing left to confirm:
+// the status is unchanged, or the change is confirmed and should be committed. The first status
+// of a pending service needs no confirmation.
+func nextAttempt(hs models.HostService, status string, retries int) int {
+	if status == hs.Status || hs.Status == "pending" || hs.Attempts+1 >= retries {
+		return 0
 	}
+	return hs.Attempts + 1
+}
+
+// setAttempts records the attempts to confirm a status change for a host service, moves it to or
+// from its retry interval, and shows the attempt on the host page
+func (repo *DBRepo) setAttempts(h models.Host, hs models.HostService, attempts int, status, msg string) {
+	err := repo.DB.UpdateHostServiceAttempts(hs.ID, attempts, status)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	wasRetrying := hs.Attempts > 0
+	hs.Attempts, hs.AttemptStatus = attempts, status
 
+	retries, interval := checkers.Retries(hs)
+	if _, scheduled := repo.App.MonitorMap[hs.ID]; scheduled && interval > 0 && wasRetrying != (attempts > 0) {
+		repo.removeFromMonitorMap(hs)
+		repo.addToMonitorMap(hs)
+	}
+
+	data := make(map[string]string)
+	data["host_id"] = strconv.Itoa(hs.HostID)
+	data["host_service_id"] = strconv.Itoa(hs.ID)
+	data["attempts"] = strconv.Itoa(attempts)
+	data["retries"] = strconv.Itoa(retries)
+	data["attempt_status"] = status
+	data["message"] = msg
+	repo.broadcastMessage("public-channel", "host-service-attempt", data)
+}
+
+// scheduleSpec returns the cron spec for a host service: its retry interval while a status change
+// is being confirmed, and otherwise its schedule
+func scheduleSpec(hs models.HostService) string {
+	if _, interval := checkers.Retries(hs); hs.Attempts > 0 && interval > 0 {
+		return fmt.Sprintf("@every %s", interval)
+	}
+	if hs.ScheduleUnit == "d" {
+		return fmt.Sprintf("@every %d%s", hs.ScheduleNumber*24, "h")
+	}
+	return fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit)
 }
 
 func (repo *DBRepo) updateHostServiceStatusCount(h models.Host, hs models.HostService, newStatus, msg string) {
@@ -72,20 +151,28 @@ func (repo *DBRepo) updateHostServiceStatusCount(h models.Host, hs models.HostSe
 		return
 	}
 
-	pending, healthy, warning, problem, err := repo.DB.GetAllServiceStatusCounts()
+	repo.pushStatusCounts()
+
+	log.Println("New status is", newStatus, "and msg is", msg)
+}
+
+// pushStatusCounts broadcasts the number of host services in each status
+func (repo *DBRepo) pushStatusCounts() {
+	counts, err := repo.DB.GetAllServiceStatusCounts()
 	if err != nil {
 		log.Println(err)
 		return
 	}
 	data := make(map[string]string)
-	data["healthy_count"] = strconv.Itoa(healthy)
-	data["pending_count"] = strconv.Itoa(pending)
-	data["problem_count"] = strconv.Itoa(problem)
-	data["warning_count"] = strconv.Itoa(warning)
+	data["healthy_count"] = strconv.Itoa(counts.Healthy)
+	data["pending_count"] = strconv.Itoa(counts.Pending)
+	data["problem_count"] = strconv.Itoa(counts.Problem)
+	data["warning_count"] = strconv.Itoa(counts.Warning)
+	data["unknown_count"] = strconv.Itoa(counts.Unknown)
+	data["unreachable_count"] = strconv.Itoa(counts.Unreachable)
+	data["flapping_count"] = strconv.Itoa(counts.Flapping)
 	log.Println(data)
 	repo.broadcastMessage("public-channel", "host-service-count-changed", data)
-
-	log.Println("New status is", newStatus, "and msg is", msg)
 }
 
 func (repo *DBRepo) broadcastMessage(channel, messageType string, data map[string]string) {
@@ -118,7 +205,8 @@ func (repo *DBRepo) TestCheck(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// test service
-	newStatus, msg := repo.testServiceForHost(h, hs)
+	res := repo.testServiceForHost(r.Context(), h, hs)
+	newStatus, msg := res.Status, res.Message
 
 	// save event
 	event := models.Event{
@@ -128,6 +216,7 @@ func (repo *DBRepo) TestCheck(w http.ResponseWriter, r *http.Request) {
 		ServiceName:   hs.Service.ServiceName,
 		HostName:      hs.HostName,
 		Message:       msg,
+		Maintenance:   maintenanceFlag(repo.inMaintenance(hs)),
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
@@ -138,7 +227,7 @@ func (repo *DBRepo) TestCheck(w http.ResponseWriter, r *http.Request) {
 
 	// broadcast service status changed event
 	if newStatus != hs.Status {
-		repo.pushStatusChangedEvent(h, hs, newStatus)
+		repo.pushStatusChangedEvent(h, hs, newStatus, res.Metrics)
 	}
 
 	// update the host service in the db with status if there is a change and last check
@@ -179,24 +268,34 @@ func (repo *DBRepo) TestCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 // testServiceForHost tests a service for a host
-func (repo *DBRepo) testServiceForHost(h models.Host, hs models.HostService) (string, string) {
-	var msg, newStatus string
-
-	switch hs.ServiceID {
-	case HTTP:
-		msg, newStatus = testHTTPForHost(h.URL)
-		break
-	case HTTPS:
-		msg, newStatus = testHTTPSForHost(h.URL)
-		break
+func (repo *DBRepo) testServiceForHost(ctx context.Context, h models.Host, hs models.HostService) checkers.Result {
+	res := checkers.Run(ctx, h, hs)
 
-	case SSLCertificate:
-		msg, newStatus = testSSLForHost(h.URL)
-		break
+	// the check was abandoned (monitoring turned off, or the request went away), so there is no
+	// new status to record
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return checkers.Result{Status: hs.Status, Message: hs.LastMessage}
 	}
+
+	res = repo.applyDependencies(hs, res)
+	repo.recordCheckResult(h, hs, res)
+	return res
+}
+
+// recordCheckResult stores the metrics and any status change event for a check of a host service,
+// broadcasts it and sends notifications when the status changed, unless the service is in
+// maintenance
+func (repo *DBRepo) recordCheckResult(h models.Host, hs models.HostService, res checkers.Result) {
+	msg, newStatus := res.Message, res.Status
+
+	repo.recordCheckData(h, hs, res)
+	wasFlapping := hs.Flapping == 1
+	hs = repo.updateFlapping(h, hs, newStatus)
+	maintenance := repo.inMaintenance(hs)
+
 	// broadcast to clients if appropriate
 	if hs.Status != newStatus {
-		repo.pushStatusChangedEvent(h, hs, newStatus)
+		repo.pushStatusChangedEvent(h, hs, newStatus, res.Metrics)
 
 		// save event
 		event := models.Event{
@@ -206,6 +305,7 @@ func (repo *DBRepo) testServiceForHost(h models.Host, hs models.HostService) (st
 			ServiceName:   hs.Service.ServiceName,
 			HostName:      hs.HostName,
 			Message:       msg,
+			Maintenance:   maintenanceFlag(maintenance),
 			CreatedAt:     time.Now(),
 			UpdatedAt:     time.Now(),
 		}
@@ -216,53 +316,77 @@ func (repo *DBRepo) testServiceForHost(h models.Host, hs models.HostService) (st
 	}
 
 	repo.pushScheduleChangedEvent(hs, newStatus)
-	// send email if appropriate
-	if repo.App.PreferenceMap["notify_via_email"] == "1" {
-		if hs.Status != "pending" {
-			mm := channeldata.MailData{
-				ToName:    repo.App.PreferenceMap["notify_name"],
-				ToAddress: repo.App.PreferenceMap["notify_email"],
-			}
-
-			if newStatus == "healthy" {
-				mm.Subject = fmt.Sprintf("HEALTHY: service %s on %s", hs.Service.ServiceName, hs.HostName)
-				mm.Content = template.HTML(fmt.Sprintf(`<p>Service %s on %s reported healthy status</p>
-					<p><strong>Message received: %s</p>`, hs.Service.ServiceName, hs.HostName, msg))
-			} else if newStatus == "problem" {
-				mm.Subject = fmt.Sprintf("PROBLEM: service %s on %s", hs.Service.ServiceName, hs.HostName)
-				mm.Content = template.HTML(fmt.Sprintf(`<p>Service %s on %s reported problem</p>
-					<p><strong>Message received: %s</p>`, hs.Service.ServiceName, hs.HostName, msg))
-			} else if newStatus == "warning" {
-				mm.Subject = fmt.Sprintf("WARNING: service %s on %s", hs.Service.ServiceName, hs.HostName)
-				mm.Content = template.HTML(fmt.Sprintf(`<p>Service %s on %s reported warning</p>
-					<p><strong>Message received: %s</p>`, hs.Service.ServiceName, hs.HostName, msg))
-			}
-			helpers.SendEmail(mm)
-		}
+
+	// notify only when the status changed, not on every check
+	if hs.Status == newStatus {
+		return
+	}
+	m := statusMessage(h, hs, newStatus, msg)
+
+	// nothing is announced while the service is flapping (the start and end of flapping are
+	// notified instead) or in maintenance, nor when it is unreachable because a parent is down,
+	// which is notified instead; that failure was never notified, so neither is its recovery.
+	// Incidents opened before are still resolved once the service leaves problem.
+	if wasFlapping || hs.Flapping == 1 || maintenance ||
+		newStatus == "unreachable" || (hs.Status == "unreachable" && newStatus == "healthy") {
+		repo.resolveIncidents(m)
+		return
 	}
 
-	// send sms if appropriate
-	if repo.App.PreferenceMap["notify_via_sms"] == "1" {
-		to := repo.App.PreferenceMap["sms_notify_number"]
-		smsMessage := ""
+	// a service's first status is not a change worth notifying
+	if hs.Status == "pending" {
+		return
+	}
 
-		if newStatus == "healthy" {
-			smsMessage = fmt.Sprintf("Service %s on %s is healthy", hs.Service.ServiceName, hs.HostName)
-		} else if newStatus == "problem" {
-			smsMessage = fmt.Sprintf("Service %s on %s reports a problem: %s", hs.Service.ServiceName, hs.HostName, msg)
-		} else if newStatus == "warning" {
-			smsMessage = fmt.Sprintf("Service %s on %s reports a warning: %s", hs.Service.ServiceName, hs.HostName, msg)
+	repo.notify(m)
+}
+
+// recordCheckData stores what a check of a host service found besides its status: metrics,
+// certificates, state to remember, and notices
+func (repo *DBRepo) recordCheckData(h models.Host, hs models.HostService, res checkers.Result) {
+	if len(res.Metrics) > 0 {
+		err := repo.DB.InsertMetrics(hs.ID, res.Metrics)
+		if err != nil {
+			log.Println(err)
 		}
-		err := sms.SendTextTwilio(to, smsMessage, repo.App)
+	}
+
+	if res.Certificates != nil {
+		err := repo.DB.ReplaceCertificates(hs.ID, certificatesForInventory(hs, res.Certificates))
 		if err != nil {
-			log.Println("Error sending sms in perform-checks.go", err)
+			log.Println(err)
 		}
 	}
 
-	return newStatus, msg
+	if res.State != "" && res.State != hs.CheckState {
+		err := repo.DB.UpdateHostServiceCheckState(hs.ID, res.State)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	maintenance := len(res.Notices) > 0 && repo.inMaintenance(hs)
+	for _, n := range res.Notices {
+		err := repo.DB.InsertEvent(models.Event{
+			EventType:     n.Type,
+			HostServiceID: hs.ID,
+			HostID:        h.ID,
+			ServiceName:   hs.Service.ServiceName,
+			HostName:      hs.HostName,
+			Message:       n.Message,
+			Maintenance:   maintenanceFlag(maintenance),
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
 }
 
-func (repo *DBRepo) pushStatusChangedEvent(h models.Host, hs models.HostService, newStatus string) {
+// pushStatusChangedEvent broadcasts a host service's new status, along with any metrics measured by
+// the check (as metric_<name>)
+func (repo *DBRepo) pushStatusChangedEvent(h models.Host, hs models.HostService, newStatus string, metrics map[string]float64) {
 	data := make(map[string]string)
 	data["host_id"] = strconv.Itoa(hs.HostID)
 	data["host_service_id"] = strconv.Itoa(hs.ID)
@@ -270,8 +394,12 @@ func (repo *DBRepo) pushStatusChangedEvent(h models.Host, hs models.HostService,
 	data["service_name"] = hs.Service.ServiceName
 	data["icon"] = hs.Service.Icon
 	data["status"] = newStatus
+	data["flapping"] = strconv.Itoa(hs.Flapping)
 	data["message"] = fmt.Sprintf("%s on %s reports %s", hs.Service.ServiceName, h.HostName, newStatus)
 	data["last_check"] = time.Now().Format("01-02-2006, 3:04:06 PM")
+	for k, v := range metrics {
+		data["metric_"+k] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
 
 	repo.broadcastMessage("public-channel", "host-service-status-changed", data)
 }
@@ -300,112 +428,11 @@ func (repo *DBRepo) pushScheduleChangedEvent(hs models.HostService, newStatus st
 	repo.broadcastMessage("public-channel", "schedule-changed-event", data)
 }
 
-func testHTTPForHost(url string) (string, string) {
-	if strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	url = strings.Replace(url, "https://", "http://", -1)
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Sprintf("%s - %s", url, "error connecting"), "problem"
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Sprintf("%s - %s", url, resp.Status), "problem"
-	}
-
-	return fmt.Sprintf("%s - %s", url, resp.Status), "healthy"
-}
-
-func testHTTPSForHost(url string) (string, string) {
-	if strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	url = strings.Replace(url, "http://", "https://", -1)
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Println("HTTPS error 1")
-		return fmt.Sprintf("%s - %s", url, "error connecting"), "problem"
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Println("HTTPS error 2", resp.StatusCode)
-		return fmt.Sprintf("%s - %s", url, resp.Status), "problem"
-	}
-
-	return fmt.Sprintf("%s - %s", url, resp.Status), "healthy"
-}
-
-// scanHost gets cert details from an internet host
-func scanHost(hostname string, certDetailsChannel chan certificateutils.CertificateDetails, errorsChannel chan error) {
-
-	res, err := certificateutils.GetCertificateDetails(hostname, 10)
-	if err != nil {
-		errorsChannel <- err
-	} else {
-		certDetailsChannel <- res
-	}
-}
-
-func testSSLForHost(url string) (string, string) {
-	if strings.HasPrefix(url, "https://") {
-		url = strings.Replace(url, "https://", "", -1)
-	}
-	if strings.HasPrefix(url, "http://") {
-		url = strings.Replace(url, "http://", "", -1)
-	}
-	var certDetailsChannel chan certificateutils.CertificateDetails
-	var errorsChannel chan error
-	certDetailsChannel = make(chan certificateutils.CertificateDetails, 1)
-	errorsChannel = make(chan error, 1)
-
-	var msg, newStatus string
-
-	scanHost(url, certDetailsChannel, errorsChannel)
-
-	for i, certDetailsInQueue := 0, len(certDetailsChannel); i < certDetailsInQueue; i++ {
-		certDetails := <-certDetailsChannel
-		certificateutils.CheckExpirationStatus(&certDetails, 30)
-
-		if certDetails.Expired {
-			// cert expired
-			msg = certDetails.Hostname + " has expired!"
-
-		} else if certDetails.ExpiringSoon {
-			// cert expiring soon
-			if certDetails.DaysUntilExpiration < 7 {
-				msg = certDetails.Hostname + " expiring in " + strconv.Itoa(certDetails.DaysUntilExpiration) + " days"
-				newStatus = "problem"
-			} else {
-				msg = certDetails.Hostname + " expiring in " + strconv.Itoa(certDetails.DaysUntilExpiration) + " days"
-				newStatus = "warning"
-			}
-
-		} else {
-			// cert okay
-			msg = certDetails.Hostname + " expiring in " + strconv.Itoa(certDetails.DaysUntilExpiration) + " days"
-			newStatus = "healthy"
-		}
-
-	}
-	if len(errorsChannel) > 0 {
-		fmt.Printf("There were %d error(s):\n", len(errorsChannel))
-		for i, errorsInChannel := 0, len(errorsChannel); i < errorsInChannel; i++ {
-			msg = fmt.Sprintf("%s\n", <-errorsChannel)
-		}
-		fmt.Printf("\n")
-		newStatus = "problem"
-	}
-	return msg, newStatus
-}
-
 func (repo *DBRepo) addToMonitorMap(hs models.HostService) {
 	if repo.App.PreferenceMap["monitoring_live"] == "1" {
 		var j job
 		j.HostServiceID = hs.ID
-		scheduleID, err := repo.App.Scheduler.AddJob(fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit), j)
+		scheduleID, err := repo.App.Scheduler.AddJob(scheduleSpec(hs), j)
 		if err != nil {
 			log.Println(err)
 			return
@@ -427,6 +454,7 @@ func (repo *DBRepo) addToMonitorMap(hs models.HostService) {
 func (repo *DBRepo) removeFromMonitorMap(hs models.HostService) {
 	if repo.App.PreferenceMap["monitoring_live"] == "1" {
 		repo.App.Scheduler.Remove(repo.App.MonitorMap[hs.ID])
+		delete(repo.App.MonitorMap, hs.ID)
 		data := make(map[string]string)
 		data["host_service_id"] = strconv.Itoa(hs.ID)
 		repo.broadcastMessage("public-channel", "schedule-item-removed-event", data)
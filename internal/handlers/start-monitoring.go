@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -11,6 +13,35 @@ type job struct {
 	HostServiceID int
 }
 
+var (
+	monitorMu     sync.Mutex
+	monitorCtx    context.Context
+	monitorCancel context.CancelFunc
+)
+
+// monitoringContext returns the context that scheduled checks run under. It is cancelled when
+// monitoring is turned off, so checks that are still running stop straight away.
+func monitoringContext() context.Context {
+	monitorMu.Lock()
+	defer monitorMu.Unlock()
+
+	if monitorCtx == nil {
+		monitorCtx, monitorCancel = context.WithCancel(context.Background())
+	}
+	return monitorCtx
+}
+
+// cancelMonitoringContext cancels any running scheduled checks
+func cancelMonitoringContext() {
+	monitorMu.Lock()
+	defer monitorMu.Unlock()
+
+	if monitorCancel != nil {
+		monitorCancel()
+	}
+	monitorCtx, monitorCancel = nil, nil
+}
+
 // Run will perform scheduled job
 func (j job) Run() {
 	Repo.ScheduledCheck(j.HostServiceID)
@@ -37,13 +68,8 @@ func (repo *DBRepo) StartMonitoring() {
 		// range through the services
 		for _, x := range servicesToMonitor {
 			log.Println("*** Service to monitor on", x.HostName, "is", x.Service.ServiceName)
-			// get the schedule unit and number
-			var sch string
-			if x.ScheduleUnit == "d" {
-				sch = fmt.Sprintf("@every %d%s", x.ScheduleNumber*24, "h")
-			} else {
-				sch = fmt.Sprintf("@every %d%s", x.ScheduleNumber, x.ScheduleUnit)
-			}
+			// get the schedule unit and number, or the retry interval
+			sch := scheduleSpec(x)
 
 			// create job
 			var j job
@@ -1,6 +1,11 @@
 package helpers
 
-import "time"
+import (
+	"time"
+
+	"github.com/wtran29/spectre/internal/checkers"
+	"github.com/wtran29/spectre/internal/models"
+)
 
 func addTemplateFunctions() {
 	views.AddGlobal("humanDate", func(t time.Time) string {
@@ -14,6 +19,15 @@ func addTemplateFunctions() {
 	views.AddGlobal("dateAfterYearOne", func(t time.Time) bool {
 		return DateAfterY1(t)
 	})
+
+	views.AddGlobal("daysUntil", func(t time.Time) int {
+		return DaysUntil(t)
+	})
+
+	views.AddGlobal("retries", func(hs models.HostService) int {
+		retries, _ := checkers.Retries(hs)
+		return retries
+	})
 }
 
 // HumanDate formats a time in YYYY-MM-DD format
@@ -34,3 +48,8 @@ func DateAfterY1(t time.Time) bool {
 	yearOne := time.Date(0001, 11, 17, 20, 34, 58, 651387237, time.UTC)
 	return t.After(yearOne)
 }
+
+// DaysUntil returns the number of whole days from now until t, negative once t has passed
+func DaysUntil(t time.Time) int {
+	return int(time.Until(t).Hours() / 24)
+}
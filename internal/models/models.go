@@ -55,11 +55,115 @@ type Host struct {
 	Location      string
 	OS            string
 	Active        int
+	AgentID       int
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	HostServices  []HostService
 }
 
+// Agent model - a remote agent that runs the checks of hosts assigned to it
+type Agent struct {
+	ID        int
+	Name      string
+	TokenHash string
+	LastSeen  time.Time
+	Active    int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// MaintenanceWindow model - a period when a host, or one of its services, is expected to be down,
+// so status changes are not notified. A one-off window runs from StartsAt to EndsAt; a recurring
+// one runs from StartTime to EndTime ("15:04") in Timezone, every day or on Weekday every week,
+// ending the next day when EndTime is not after StartTime.
+type MaintenanceWindow struct {
+	ID            int
+	Description   string
+	HostID        int
+	HostServiceID int
+	Recurrence    string
+	StartsAt      time.Time
+	EndsAt        time.Time
+	Weekday       int
+	StartTime     string
+	EndTime       string
+	Timezone      string
+	HostName      string
+	ServiceName   string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Dependency model - a host, or a single host service, that cannot be reached when its parent
+// host or host service is down. A HostServiceID or ParentHostServiceID of 0 means the whole host.
+type Dependency struct {
+	ID                  int
+	HostID              int
+	HostServiceID       int
+	ParentHostID        int
+	ParentHostServiceID int
+	HostName            string
+	ServiceName         string
+	ParentHostName      string
+	ParentServiceName   string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// NotificationChannel model - somewhere status changes are sent, such as an email address or a
+// phone number. ChannelType picks the notifier that sends to it, and Config holds that notifier's
+// settings as a JSON object.
+type NotificationChannel struct {
+	ID          int
+	Name        string
+	ChannelType string
+	Config      string
+	Enabled     int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// WebhookDelivery model - one attempt, with its retries, to post a notification to a webhook
+// channel. Payload is the body that was posted, so the delivery can be replayed.
+type WebhookDelivery struct {
+	ID                    int
+	NotificationChannelID int
+	EventType             string
+	HostServiceID         int
+	Payload               string
+	Attempts              int
+	StatusCode            int
+	Error                 string
+	Delivered             int
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// Certificate model - a certificate in the inventory, as last seen by a check of a host service
+type Certificate struct {
+	ID            int
+	HostServiceID int
+	Source        string
+	Subject       string
+	Issuer        string
+	SerialNumber  string
+	Thumbprint    string
+	DNSNames      string
+	NotAfter      time.Time
+	KeyMismatch   bool
+	HostID        int
+	HostName      string
+	ServiceName   string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// CertificateFilter narrows the certificate inventory; zero values match every certificate
+type CertificateFilter struct {
+	Issuer        string
+	ExpiresBefore time.Time
+}
+
 // Services model
 type Services struct {
 	ID          int
@@ -72,19 +176,42 @@ type Services struct {
 
 // HostService model - link service and host
 type HostService struct {
-	ID             int
-	HostID         int
-	ServiceID      int
-	Active         int
-	ScheduleNumber int
-	ScheduleUnit   string
-	Status         string
-	LastCheck      time.Time
-	LastMessage    string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Service        Services
-	HostName       string
+	ID               int
+	HostID           int
+	ServiceID        int
+	Active           int
+	ScheduleNumber   int
+	ScheduleUnit     string
+	Status           string
+	LastCheck        time.Time
+	LastMessage      string
+	Config           string
+	HeartbeatToken   string
+	LastHeartbeat    time.Time
+	HeartbeatOK      int
+	HeartbeatMessage string
+	CheckState       string
+	Attempts         int
+	AttemptStatus    string
+	StatusHistory    string
+	FlapScore        float64
+	Flapping         int
+	ActivatedAt      time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Service          Services
+	HostName         string
+}
+
+// ServiceStatusCounts is the number of active host services in each status
+type ServiceStatusCounts struct {
+	Pending     int
+	Healthy     int
+	Warning     int
+	Problem     int
+	Unknown     int
+	Unreachable int
+	Flapping    int
 }
 
 // Schedule model
@@ -108,6 +235,7 @@ type Event struct {
 	ServiceName   string
 	HostName      string
 	Message       string
+	Maintenance   int
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
@@ -0,0 +1,68 @@
+// Package emailnotifier sends notifications by email, through the mail queue of the web server
+package emailnotifier
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/mail"
+
+	"github.com/wtran29/spectre/internal/channeldata"
+	"github.com/wtran29/spectre/internal/helpers"
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+func init() {
+	notifiers.Register("email", "Email", &Notifier{})
+}
+
+// Config is the per channel configuration for email: who receives the messages
+type Config struct {
+	ToName    string `json:"to_name"`
+	ToAddress string `json:"to_address"`
+}
+
+// Notifier queues notification emails
+type Notifier struct{}
+
+// Notify queues an email to the channel's recipient
+func (n *Notifier) Notify(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+
+	content := msg.HTML
+	if content == "" {
+		content = template.HTML(fmt.Sprintf("<p>%s</p>", template.HTMLEscapeString(msg.Text)))
+	}
+
+	helpers.SendEmail(channeldata.MailData{
+		ToName:    cfg.ToName,
+		ToAddress: cfg.ToAddress,
+		Subject:   msg.Subject,
+		Content:   content,
+	})
+	return nil
+}
+
+// Fields describes the settings of an email channel
+func (n *Notifier) Fields() []notifiers.Field {
+	return []notifiers.Field{
+		{Name: "to_name", Label: "Recipient's Name"},
+		{Name: "to_address", Label: "Recipient's Email Address", Placeholder: "ops@example.com", Required: true},
+	}
+}
+
+// Validate checks the recipient's email address
+func (n *Notifier) Validate(ch models.NotificationChannel) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	if _, err := mail.ParseAddress(cfg.ToAddress); err != nil {
+		return fmt.Errorf("invalid email address %q", cfg.ToAddress)
+	}
+	return nil
+}
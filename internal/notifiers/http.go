@@ -0,0 +1,58 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// maxErrorBytes caps how much of an error response is kept to describe the failure
+const maxErrorBytes = 512
+
+// PostJSON posts payload as JSON to target, and returns an error unless the response status is 2xx
+func PostJSON(ctx context.Context, target string, payload interface{}) error {
+	return Post(ctx, target, nil, payload)
+}
+
+// Post posts payload as JSON to target with extra request headers, and returns an error unless the
+// response status is 2xx
+func Post(ctx context.Context, target string, header http.Header, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, bytes.TrimSpace(b))
+	}
+	return nil
+}
+
+// ValidURL returns an error unless s is an absolute http or https url
+func ValidURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q", s)
+	}
+	return nil
+}
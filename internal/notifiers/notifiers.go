@@ -0,0 +1,283 @@
+// Package notifiers holds the registry of the ways spectre can tell people about status changes.
+// Each channel type lives in its own package and registers itself here, keyed by the channel_type
+// of the rows in the notification_channels table that use it.
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// Notifier is implemented by every type of notification channel
+type Notifier interface {
+	// Notify sends a message to a notification channel
+	Notify(ctx context.Context, ch models.NotificationChannel, msg Message) error
+}
+
+// Validator is implemented by notifiers that can verify a channel's settings before it is saved
+type Validator interface {
+	Validate(ch models.NotificationChannel) error
+}
+
+// Field describes one of the settings of a channel type, for the form that configures channels.
+// Multiline settings are edited in a text area, and Help is shown below the setting.
+type Field struct {
+	Name        string
+	Label       string
+	Placeholder string
+	Help        string
+	Required    bool
+	Multiline   bool
+}
+
+// Timeouter is implemented by notifiers that may take longer than DefaultTimeout to send, such as
+// ones that retry
+type Timeouter interface {
+	Timeout(ch models.NotificationChannel) time.Duration
+}
+
+// IncidentTracker is implemented by notifiers that keep an incident open while a host service is
+// in problem. Status changes that are not announced, such as a recovery during maintenance, are
+// still passed to ResolveIncident, so that no incident is left open.
+type IncidentTracker interface {
+	ResolveIncident(ctx context.Context, ch models.NotificationChannel, msg Message) error
+}
+
+// Describer is implemented by notifiers whose channels have settings. The settings are stored as
+// a JSON object of strings, keyed by field name.
+type Describer interface {
+	Fields() []Field
+}
+
+// Message is a notification about a host service. EventType is the type of event that was recorded
+// for it: the new status, or the start or end of flapping. Detail is what the check reported, and
+// URL links to the host's page.
+//
+// Channels that can show formatting send HTML, or build their own from the fields; the others send
+// Text, which is kept short enough for a text message.
+type Message struct {
+	EventType     string
+	HostID        int
+	HostServiceID int
+	HostName      string
+	ServiceName   string
+	OldStatus     string
+	Status        string
+	Subject       string
+	Detail        string
+	Text          string
+	HTML          template.HTML
+	URL           string
+	Time          time.Time
+}
+
+// Severity sums up a message for channels that colour it: "good", "warning", "danger" or
+// "neutral"
+func (m Message) Severity() string {
+	switch {
+	case m.EventType == "flapping-started":
+		return "warning"
+	case m.Status == "healthy":
+		return "good"
+	case m.Status == "warning":
+		return "warning"
+	case m.Status == "problem":
+		return "danger"
+	}
+	return "neutral"
+}
+
+// DedupKey identifies the host service of a message to incident tools, so that its repeated
+// failures update one incident rather than opening more
+func (m Message) DedupKey() string {
+	return fmt.Sprintf("spectre-host-service-%d", m.HostServiceID)
+}
+
+// OpensIncident reports whether a message means its host service is in problem: a change to
+// problem, or the end of flapping on problem
+func (m Message) OpensIncident() bool {
+	return m.Status == "problem" && (m.EventType == "problem" || m.EventType == "flapping-stopped")
+}
+
+// ResolvesIncident reports whether a message means its host service has left problem: a change
+// from problem (or from unreachable, which hides it) to any other status, or the end of flapping
+// on a status other than problem
+func (m Message) ResolvesIncident() bool {
+	switch m.EventType {
+	case "test", "flapping-started":
+		return false
+	case "flapping-stopped":
+		return m.Status != "problem"
+	}
+	return (m.OldStatus == "problem" || m.OldStatus == "unreachable") && m.Status != "problem"
+}
+
+// DefaultTimeout is how long sending a notification to a channel may take
+const DefaultTimeout = 10 * time.Second
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Notifier)
+	labels   = make(map[string]string)
+)
+
+// Register makes a notifier available under the given channel type, with a label to show for it.
+// It panics if the type is registered twice or the notifier is nil, in the same way database/sql
+// handles drivers.
+func Register(channelType, label string, n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if n == nil {
+		panic("notifiers: Register notifier is nil")
+	}
+
+	key := normalize(channelType)
+	if _, dup := registry[key]; dup {
+		panic("notifiers: Register called twice for " + channelType)
+	}
+	registry[key] = n
+	labels[key] = label
+}
+
+// Get returns the notifier registered for a channel type
+func Get(channelType string) (Notifier, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	n, ok := registry[normalize(channelType)]
+	return n, ok
+}
+
+// Type is a registered channel type and the settings its channels have
+type Type struct {
+	Name   string
+	Label  string
+	Fields []Field
+}
+
+// Types returns the registered channel types, sorted by label
+func Types() []Type {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var list []Type
+	for key, n := range registry {
+		t := Type{Name: key, Label: labels[key]}
+		if d, ok := n.(Describer); ok {
+			t.Fields = d.Fields()
+		}
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Label < list[j].Label })
+	return list
+}
+
+// Label returns the label of a channel type, or the type itself when it is not registered
+func Label(channelType string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if l, ok := labels[normalize(channelType)]; ok {
+		return l
+	}
+	return channelType
+}
+
+// Send notifies a channel with the notifier registered for its type
+func Send(ctx context.Context, ch models.NotificationChannel, msg Message) error {
+	n, ok := Get(ch.ChannelType)
+	if !ok {
+		return fmt.Errorf("no notifier registered for channel type %s", ch.ChannelType)
+	}
+	return n.Notify(ctx, ch, msg)
+}
+
+// TracksIncidents reports whether the notifier registered for a channel type keeps incidents open
+func TracksIncidents(channelType string) bool {
+	n, ok := Get(channelType)
+	if !ok {
+		return false
+	}
+	_, ok = n.(IncidentTracker)
+	return ok
+}
+
+// ResolveIncident resolves any incident open for the host service of a message on a channel whose
+// notifier tracks incidents. Other channels are left alone.
+func ResolveIncident(ctx context.Context, ch models.NotificationChannel, msg Message) error {
+	n, ok := Get(ch.ChannelType)
+	if !ok {
+		return fmt.Errorf("no notifier registered for channel type %s", ch.ChannelType)
+	}
+	if t, ok := n.(IncidentTracker); ok {
+		return t.ResolveIncident(ctx, ch, msg)
+	}
+	return nil
+}
+
+// Timeout returns how long sending a notification to a channel may take
+func Timeout(ch models.NotificationChannel) time.Duration {
+	n, ok := Get(ch.ChannelType)
+	if !ok {
+		return DefaultTimeout
+	}
+
+	if t, ok := n.(Timeouter); ok {
+		return t.Timeout(ch)
+	}
+	return DefaultTimeout
+}
+
+// Validate verifies the settings of a channel: its type must be registered, its required fields
+// set, and its notifier must accept it when that notifier implements Validator
+func Validate(ch models.NotificationChannel) error {
+	n, ok := Get(ch.ChannelType)
+	if !ok {
+		return fmt.Errorf("unknown channel type %s", ch.ChannelType)
+	}
+
+	var cfg map[string]string
+	if err := DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	if d, ok := n.(Describer); ok {
+		for _, f := range d.Fields() {
+			if f.Required && strings.TrimSpace(cfg[f.Name]) == "" {
+				return fmt.Errorf("%s is required", f.Label)
+			}
+		}
+	}
+
+	if v, ok := n.(Validator); ok {
+		return v.Validate(ch)
+	}
+	return nil
+}
+
+// DecodeConfig unmarshals the settings stored with a channel into cfg. Empty settings leave cfg
+// untouched, so callers should set their defaults before calling.
+func DecodeConfig(ch models.NotificationChannel, cfg interface{}) error {
+	if strings.TrimSpace(ch.Config) == "" {
+		return nil
+	}
+
+	err := json.Unmarshal([]byte(ch.Config), cfg)
+	if err != nil {
+		return fmt.Errorf("invalid settings for %s: %w", ch.Name, err)
+	}
+	return nil
+}
+
+// normalize makes registry lookups insensitive to case and surrounding space
+func normalize(channelType string) string {
+	return strings.ToLower(strings.TrimSpace(channelType))
+}
@@ -0,0 +1,125 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+type dummyNotifier struct {
+	sent []Message
+}
+
+func (d *dummyNotifier) Notify(ctx context.Context, ch models.NotificationChannel, msg Message) error {
+	d.sent = append(d.sent, msg)
+	return nil
+}
+
+func (d *dummyNotifier) Fields() []Field {
+	return []Field{{Name: "url", Label: "URL", Required: true}, {Name: "channel", Label: "Channel"}}
+}
+
+func (d *dummyNotifier) Validate(ch models.NotificationChannel) error {
+	if ch.Name == "invalid" {
+		return errors.New("invalid")
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	d := &dummyNotifier{}
+	Register("Dummy", "Dummy Channel", d)
+
+	if _, ok := Get("dummy"); !ok {
+		t.Error("expected to find notifier registered as Dummy")
+	}
+
+	if _, ok := Get("not registered"); ok {
+		t.Error("found a notifier that was never registered")
+	}
+
+	found := false
+	for _, typ := range Types() {
+		if typ.Name == "dummy" && typ.Label == "Dummy Channel" && len(typ.Fields) == 2 {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("dummy missing from Types()")
+	}
+
+	if l := Label("DUMMY"); l != "Dummy Channel" {
+		t.Errorf("expected label Dummy Channel, but got %s", l)
+	}
+
+	err := Send(context.Background(), models.NotificationChannel{ChannelType: "dummy"}, Message{Subject: "hello"})
+	if err != nil || len(d.sent) != 1 || d.sent[0].Subject != "hello" {
+		t.Errorf("expected message to be sent, but got %v and %v", err, d.sent)
+	}
+
+	err = Send(context.Background(), models.NotificationChannel{ChannelType: "carrier pigeon"}, Message{})
+	if err == nil {
+		t.Error("expected error sending to an unregistered channel type")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic when registering the same type twice")
+		}
+	}()
+	Register("DUMMY", "Dummy Again", &dummyNotifier{})
+}
+
+func TestValidate(t *testing.T) {
+	Register("validated", "Validated", &dummyNotifier{})
+
+	var tests = []struct {
+		name        string
+		channel     models.NotificationChannel
+		expectError bool
+	}{
+		{"valid", models.NotificationChannel{Name: "ok", ChannelType: "validated", Config: `{"url": "https://example.com"}`}, false},
+		{"missing-required", models.NotificationChannel{Name: "ok", ChannelType: "validated", Config: `{"channel": "#ops"}`}, true},
+		{"blank-required", models.NotificationChannel{Name: "ok", ChannelType: "validated", Config: `{"url": "  "}`}, true},
+		{"notifier-rejects", models.NotificationChannel{Name: "invalid", ChannelType: "validated", Config: `{"url": "https://example.com"}`}, true},
+		{"bad-json", models.NotificationChannel{Name: "ok", ChannelType: "validated", Config: `{"url": `}, true},
+		{"unknown-type", models.NotificationChannel{Name: "ok", ChannelType: "telegraph"}, true},
+	}
+
+	for _, e := range tests {
+		err := Validate(e.channel)
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
+
+func TestIncidents(t *testing.T) {
+	var tests = []struct {
+		name             string
+		msg              Message
+		expectedOpens    bool
+		expectedResolves bool
+	}{
+		{"failure", Message{EventType: "problem", OldStatus: "healthy", Status: "problem"}, true, false},
+		{"recovery", Message{EventType: "healthy", OldStatus: "problem", Status: "healthy"}, false, true},
+		{"problem-to-warning", Message{EventType: "warning", OldStatus: "problem", Status: "warning"}, false, true},
+		{"unreachable-recovery", Message{EventType: "healthy", OldStatus: "unreachable", Status: "healthy"}, false, true},
+		{"warning", Message{EventType: "warning", OldStatus: "healthy", Status: "warning"}, false, false},
+		{"flapping-started", Message{EventType: "flapping-started", OldStatus: "problem", Status: "healthy"}, false, false},
+		{"flapping-stopped-healthy", Message{EventType: "flapping-stopped", OldStatus: "healthy", Status: "healthy"}, false, true},
+		{"flapping-stopped-problem", Message{EventType: "flapping-stopped", OldStatus: "healthy", Status: "problem"}, true, false},
+		{"test", Message{EventType: "test", OldStatus: "problem", Status: "healthy"}, false, false},
+	}
+
+	for _, e := range tests {
+		if got := e.msg.OpensIncident(); got != e.expectedOpens {
+			t.Errorf("%s: expected opens %t, but got %t", e.name, e.expectedOpens, got)
+		}
+		if got := e.msg.ResolvesIncident(); got != e.expectedResolves {
+			t.Errorf("%s: expected resolves %t, but got %t", e.name, e.expectedResolves, got)
+		}
+	}
+}
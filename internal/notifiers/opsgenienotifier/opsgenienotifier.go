@@ -0,0 +1,176 @@
+// Package opsgenienotifier opens and closes Opsgenie alerts through the Alert API. A host service
+// that reports a problem creates an alert, and the alert is closed when the service leaves problem
+// for any other status, including changes that are not announced during maintenance or flapping;
+// other notifications are not sent. Alerts for a host service share an alias, so repeated failures
+// do not open more alerts.
+package opsgenienotifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+func init() {
+	notifiers.Register("opsgenie", "Opsgenie", &Notifier{})
+}
+
+// DefaultAPIURL is where alerts are sent when a channel does not say
+const DefaultAPIURL = "https://api.opsgenie.com"
+
+// maxMessageLength is the longest alert message Opsgenie accepts
+const maxMessageLength = 130
+
+// Config is the per channel configuration for Opsgenie: the API key of the integration alerts are
+// sent through, where the Alert API is, and the priority of new alerts
+type Config struct {
+	APIKey   string `json:"api_key"`
+	APIURL   string `json:"api_url"`
+	Priority string `json:"priority"`
+}
+
+// base returns the url of the Alert API
+func (c Config) base() string {
+	base := strings.TrimRight(c.APIURL, "/")
+	if base == "" {
+		base = DefaultAPIURL
+	}
+	return base + "/v2/alerts"
+}
+
+// header returns the headers that authenticate requests with the channel's API key
+func (c Config) header() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "GenieKey "+c.APIKey)
+	return header
+}
+
+// Alert is a request to create an alert
+type Alert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// Close is a request to close an alert
+type Close struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Notifier sends alerts to Opsgenie
+type Notifier struct{}
+
+// Notify creates an alert for a problem and closes it once the service leaves problem, whatever its
+// new status. A test notification creates an alert and closes it straight away.
+func (n *Notifier) Notify(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+
+	switch {
+	case msg.EventType == "test":
+		if err := notifiers.Post(ctx, cfg.base(), cfg.header(), NewAlert(cfg.Priority, msg)); err != nil {
+			return err
+		}
+		return closeAlert(ctx, cfg, msg)
+	case msg.OpensIncident():
+		return notifiers.Post(ctx, cfg.base(), cfg.header(), NewAlert(cfg.Priority, msg))
+	case msg.ResolvesIncident():
+		return closeAlert(ctx, cfg, msg)
+	}
+	return nil
+}
+
+// ResolveIncident closes the alert for a message's host service when the message means the service
+// has left problem
+func (n *Notifier) ResolveIncident(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	if !msg.ResolvesIncident() {
+		return nil
+	}
+
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	return closeAlert(ctx, cfg, msg)
+}
+
+// closeAlert closes the alert for a message's host service, found by its alias
+func closeAlert(ctx context.Context, cfg Config, msg notifiers.Message) error {
+	target := fmt.Sprintf("%s/%s/close?identifierType=alias", cfg.base(), url.PathEscape(msg.DedupKey()))
+	return notifiers.Post(ctx, target, cfg.header(), Close{Source: "spectre", Note: msg.Text})
+}
+
+// NewAlert builds the request that creates an alert for a message
+func NewAlert(priority string, msg notifiers.Message) Alert {
+	message := msg.Subject
+	if r := []rune(message); len(r) > maxMessageLength {
+		message = string(r[:maxMessageLength])
+	}
+
+	a := Alert{
+		Message:     message,
+		Alias:       msg.DedupKey(),
+		Description: msg.Detail,
+		Entity:      msg.HostName,
+		Source:      "spectre",
+		Priority:    priority,
+		Tags:        []string{"spectre"},
+		Details: map[string]string{
+			"host":       msg.HostName,
+			"service":    msg.ServiceName,
+			"old_status": msg.OldStatus,
+			"status":     msg.Status,
+		},
+	}
+	if msg.URL != "" {
+		a.Details["url"] = msg.URL
+	}
+	if msg.EventType == "test" {
+		a.Priority = "P5"
+	}
+	return a
+}
+
+// Fields describes the settings of an Opsgenie channel
+func (n *Notifier) Fields() []notifiers.Field {
+	return []notifiers.Field{
+		{Name: "api_key", Label: "API Key", Required: true,
+			Help: "The API key of an API integration in Opsgenie."},
+		{Name: "priority", Label: "Priority", Placeholder: "P3",
+			Help: "The priority of new alerts, P1 to P5. Leave empty for the integration's default."},
+		{Name: "api_url", Label: "Alert API URL", Placeholder: DefaultAPIURL,
+			Help: "Leave empty to use Opsgenie; accounts in the EU use https://api.eu.opsgenie.com."},
+	}
+}
+
+// Validate checks the priority and the alert api url
+func (n *Notifier) Validate(ch models.NotificationChannel) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+
+	switch cfg.Priority {
+	case "", "P1", "P2", "P3", "P4", "P5":
+	default:
+		return fmt.Errorf("invalid priority %q, expected P1 to P5", cfg.Priority)
+	}
+
+	if cfg.APIURL == "" {
+		return nil
+	}
+	return notifiers.ValidURL(cfg.APIURL)
+}
@@ -0,0 +1,143 @@
+package opsgenienotifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+// standIn keeps open alerts by alias, as the Alert API would
+type standIn struct {
+	mu       sync.Mutex
+	open     map[string]Alert
+	requests []string
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "GenieKey k3y" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+
+	switch {
+	case r.URL.Path == "/v2/alerts":
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.open[a.Alias] = a
+	case strings.HasSuffix(r.URL.Path, "/close") && r.URL.Query().Get("identifierType") == "alias":
+		alias := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/alerts/"), "/close")
+		delete(s.open, alias)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"result":"Request will be processed","took":0.1,"requestId":"1"}`))
+}
+
+func TestNotify(t *testing.T) {
+	s := &standIn{open: make(map[string]Alert)}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	ch := models.NotificationChannel{Name: "ops", ChannelType: "opsgenie", Config: `{"api_key": "k3y", "priority": "P2", "api_url": "` + srv.URL + `"}`}
+	msg := func(eventType, oldStatus, status string) notifiers.Message {
+		return notifiers.Message{
+			EventType:     eventType,
+			HostServiceID: 7,
+			HostName:      "web",
+			ServiceName:   "HTTP",
+			OldStatus:     oldStatus,
+			Status:        status,
+			Subject:       "PROBLEM: service HTTP on web",
+			Detail:        "connection refused",
+		}
+	}
+
+	for _, m := range []notifiers.Message{
+		msg("problem", "healthy", "problem"),
+		msg("flapping-stopped", "problem", "problem"),
+		msg("unknown", "healthy", "unknown"),
+	} {
+		if err := (&Notifier{}).Notify(context.Background(), ch, m); err != nil {
+			t.Fatalf("%s: unexpected error: %s", m.EventType, err)
+		}
+	}
+	if len(s.requests) != 2 || len(s.open) != 1 {
+		t.Fatalf("expected two requests for one alert, but got %v and %d open alerts", s.requests, len(s.open))
+	}
+	a := s.open["spectre-host-service-7"]
+	if a.Priority != "P2" || a.Entity != "web" || a.Description != "connection refused" || a.Details["service"] != "HTTP" {
+		t.Errorf("unexpected alert %+v", a)
+	}
+
+	// leaving problem for any status closes the alert
+	if err := (&Notifier{}).Notify(context.Background(), ch, msg("warning", "problem", "warning")); err != nil {
+		t.Fatalf("warning: unexpected error: %s", err)
+	}
+	if len(s.open) != 0 {
+		t.Errorf("expected alert to be closed, but got %v", s.open)
+	}
+	if last := s.requests[len(s.requests)-1]; last != "POST /v2/alerts/spectre-host-service-7/close?identifierType=alias" {
+		t.Errorf("unexpected close request %s", last)
+	}
+
+	// a recovery that was not announced, such as one during maintenance, still closes the alert
+	s.open["spectre-host-service-7"] = a
+	if err := (&Notifier{}).ResolveIncident(context.Background(), ch, msg("healthy", "problem", "healthy")); err != nil {
+		t.Fatalf("resolve: unexpected error: %s", err)
+	}
+	if len(s.open) != 0 {
+		t.Errorf("expected alert to be closed by ResolveIncident, but got %v", s.open)
+	}
+
+	ch.Config = `{"api_key": "wrong", "api_url": "` + srv.URL + `"}`
+	if err := (&Notifier{}).Notify(context.Background(), ch, msg("problem", "healthy", "problem")); err == nil {
+		t.Error("expected error when the api key is rejected")
+	}
+}
+
+func TestNewAlert(t *testing.T) {
+	msg := notifiers.Message{EventType: "problem", HostServiceID: 3, Subject: strings.Repeat("x", 200)}
+	a := NewAlert("", msg)
+	if len(a.Message) != maxMessageLength {
+		t.Errorf("expected message cut to %d characters, but got %d", maxMessageLength, len(a.Message))
+	}
+	if a.Alias != "spectre-host-service-3" {
+		t.Errorf("expected alias spectre-host-service-3, but got %s", a.Alias)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{"defaults", `{"api_key": "k3y"}`, false},
+		{"eu", `{"api_key": "k3y", "api_url": "https://api.eu.opsgenie.com", "priority": "P1"}`, false},
+		{"bad-priority", `{"api_key": "k3y", "priority": "urgent"}`, true},
+		{"bad-url", `{"api_key": "k3y", "api_url": "api.opsgenie.com"}`, true},
+	}
+
+	for _, e := range tests {
+		err := (&Notifier{}).Validate(models.NotificationChannel{Config: e.config})
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
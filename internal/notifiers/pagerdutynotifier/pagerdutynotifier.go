@@ -0,0 +1,171 @@
+// Package pagerdutynotifier opens and resolves PagerDuty incidents through the Events API v2. A
+// host service that reports a problem triggers an incident, and the incident is resolved when the
+// service leaves problem for any other status, including changes that are not announced during
+// maintenance or flapping; other notifications are not sent. Events for a host service share a
+// dedup key, so repeated failures do not open more incidents.
+package pagerdutynotifier
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+func init() {
+	notifiers.Register("pagerduty", "PagerDuty", &Notifier{})
+}
+
+// DefaultAPIURL is where events are sent when a channel does not say
+const DefaultAPIURL = "https://events.pagerduty.com"
+
+// Config is the per channel configuration for PagerDuty: the integration key of the service that
+// incidents are opened on, and where the Events API is
+type Config struct {
+	RoutingKey string `json:"routing_key"`
+	APIURL     string `json:"api_url"`
+}
+
+// endpoint returns the url events are posted to
+func (c Config) endpoint() string {
+	base := strings.TrimRight(c.APIURL, "/")
+	if base == "" {
+		base = DefaultAPIURL
+	}
+	return base + "/v2/enqueue"
+}
+
+// Event is an Events API v2 request
+type Event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *Payload `json:"payload,omitempty"`
+	Client      string   `json:"client,omitempty"`
+	ClientURL   string   `json:"client_url,omitempty"`
+	Links       []Link   `json:"links,omitempty"`
+}
+
+// Payload describes what triggered an incident
+type Payload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// Link is a link shown with an incident
+type Link struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Notifier sends events to PagerDuty
+type Notifier struct{}
+
+// Notify triggers an incident for a problem and resolves it once the service leaves problem,
+// whatever its new status. A test notification triggers an incident and resolves it straight away.
+func (n *Notifier) Notify(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+
+	switch {
+	case msg.EventType == "test":
+		if err := notifiers.PostJSON(ctx, cfg.endpoint(), Trigger(cfg.RoutingKey, msg)); err != nil {
+			return err
+		}
+		return notifiers.PostJSON(ctx, cfg.endpoint(), Resolve(cfg.RoutingKey, msg))
+	case msg.OpensIncident():
+		return notifiers.PostJSON(ctx, cfg.endpoint(), Trigger(cfg.RoutingKey, msg))
+	case msg.ResolvesIncident():
+		return notifiers.PostJSON(ctx, cfg.endpoint(), Resolve(cfg.RoutingKey, msg))
+	}
+	return nil
+}
+
+// ResolveIncident resolves the incident for a message's host service when the message means the
+// service has left problem
+func (n *Notifier) ResolveIncident(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	if !msg.ResolvesIncident() {
+		return nil
+	}
+
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	return notifiers.PostJSON(ctx, cfg.endpoint(), Resolve(cfg.RoutingKey, msg))
+}
+
+// Trigger builds the event that opens an incident for a message
+func Trigger(routingKey string, msg notifiers.Message) Event {
+	source := msg.HostName
+	if source == "" {
+		source = "spectre"
+	}
+
+	e := Event{
+		RoutingKey:  routingKey,
+		EventAction: "trigger",
+		DedupKey:    msg.DedupKey(),
+		Payload: &Payload{
+			Summary:   msg.Subject,
+			Source:    source,
+			Severity:  "critical",
+			Component: msg.ServiceName,
+			CustomDetails: map[string]string{
+				"message":    msg.Detail,
+				"old_status": msg.OldStatus,
+				"status":     msg.Status,
+			},
+		},
+		Client:    "spectre",
+		ClientURL: msg.URL,
+	}
+	if !msg.Time.IsZero() {
+		e.Payload.Timestamp = msg.Time.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	if msg.EventType == "test" {
+		e.Payload.Severity = "info"
+	}
+	if msg.URL != "" {
+		e.Links = []Link{{Href: msg.URL, Text: "View host in spectre"}}
+	}
+	return e
+}
+
+// Resolve builds the event that resolves the incident for a message's host service
+func Resolve(routingKey string, msg notifiers.Message) Event {
+	return Event{
+		RoutingKey:  routingKey,
+		EventAction: "resolve",
+		DedupKey:    msg.DedupKey(),
+	}
+}
+
+// Fields describes the settings of a PagerDuty channel
+func (n *Notifier) Fields() []notifiers.Field {
+	return []notifiers.Field{
+		{Name: "routing_key", Label: "Integration Key", Required: true,
+			Help: "The Events API v2 integration key of the PagerDuty service."},
+		{Name: "api_url", Label: "Events API URL", Placeholder: DefaultAPIURL,
+			Help: "Leave empty to use PagerDuty."},
+	}
+}
+
+// Validate checks the events api url, when one is set
+func (n *Notifier) Validate(ch models.NotificationChannel) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	if cfg.APIURL == "" {
+		return nil
+	}
+	return notifiers.ValidURL(cfg.APIURL)
+}
@@ -0,0 +1,159 @@
+package pagerdutynotifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+// standIn records the events posted to it, as the Events API would receive them
+type standIn struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v2/enqueue" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var e Event
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil || e.RoutingKey != "R0UT1NG" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.events = append(s.events, e)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"status":"success","message":"Event processed"}`))
+}
+
+func TestNotify(t *testing.T) {
+	s := &standIn{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	ch := models.NotificationChannel{Name: "on-call", ChannelType: "pagerduty", Config: `{"routing_key": "R0UT1NG", "api_url": "` + srv.URL + `/"}`}
+	msg := func(eventType, oldStatus, status string) notifiers.Message {
+		return notifiers.Message{
+			EventType:     eventType,
+			HostServiceID: 7,
+			HostName:      "web",
+			ServiceName:   "HTTP",
+			OldStatus:     oldStatus,
+			Status:        status,
+			Subject:       "service HTTP on web",
+			Detail:        "connection refused",
+			URL:           "https://spectre.example.com/admin/host/2",
+		}
+	}
+
+	var changes = []struct {
+		eventType string
+		oldStatus string
+		status    string
+	}{
+		{"problem", "healthy", "problem"},
+		{"warning", "problem", "warning"},
+		{"problem", "warning", "problem"},
+		{"flapping-started", "problem", "problem"},
+		{"flapping-stopped", "problem", "healthy"},
+		{"warning", "healthy", "warning"},
+	}
+	for _, c := range changes {
+		err := (&Notifier{}).Notify(context.Background(), ch, msg(c.eventType, c.oldStatus, c.status))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.eventType, err)
+		}
+	}
+
+	var actions []string
+	for _, e := range s.events {
+		actions = append(actions, e.EventAction)
+		if e.DedupKey != "spectre-host-service-7" {
+			t.Errorf("%s: expected dedup key spectre-host-service-7, but got %s", e.EventAction, e.DedupKey)
+		}
+	}
+	if strings.Join(actions, ",") != "trigger,resolve,trigger,resolve" {
+		t.Fatalf("expected trigger, resolve, trigger, resolve, but got %v", actions)
+	}
+
+	p := s.events[0].Payload
+	if p == nil || p.Source != "web" || p.Severity != "critical" || p.Component != "HTTP" || p.CustomDetails["message"] != "connection refused" {
+		t.Errorf("unexpected trigger payload %+v", p)
+	}
+	if len(s.events[0].Links) != 1 || s.events[0].Links[0].Href != "https://spectre.example.com/admin/host/2" {
+		t.Errorf("expected link to the host, but got %+v", s.events[0].Links)
+	}
+	if s.events[1].Payload != nil {
+		t.Errorf("expected resolve without payload, but got %+v", s.events[1].Payload)
+	}
+}
+
+func TestResolveIncident(t *testing.T) {
+	s := &standIn{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	ch := models.NotificationChannel{Name: "on-call", ChannelType: "pagerduty", Config: `{"routing_key": "R0UT1NG", "api_url": "` + srv.URL + `"}`}
+	var tests = []struct {
+		name     string
+		msg      notifiers.Message
+		resolved bool
+	}{
+		{"maintenance-recovery", notifiers.Message{EventType: "healthy", HostServiceID: 7, OldStatus: "problem", Status: "healthy"}, true},
+		{"unreachable-recovery", notifiers.Message{EventType: "healthy", HostServiceID: 7, OldStatus: "unreachable", Status: "healthy"}, true},
+		{"failure", notifiers.Message{EventType: "problem", HostServiceID: 7, OldStatus: "healthy", Status: "problem"}, false},
+	}
+
+	for _, e := range tests {
+		before := len(s.events)
+		if err := (&Notifier{}).ResolveIncident(context.Background(), ch, e.msg); err != nil {
+			t.Fatalf("%s: unexpected error: %s", e.name, err)
+		}
+		resolved := len(s.events) > before && s.events[len(s.events)-1].EventAction == "resolve"
+		if resolved != e.resolved {
+			t.Errorf("%s: expected resolved %t, but got %t", e.name, e.resolved, resolved)
+		}
+	}
+}
+
+func TestNotifyError(t *testing.T) {
+	s := &standIn{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	ch := models.NotificationChannel{Name: "on-call", ChannelType: "pagerduty", Config: `{"routing_key": "wrong", "api_url": "` + srv.URL + `"}`}
+	err := (&Notifier{}).Notify(context.Background(), ch, notifiers.Message{EventType: "problem", HostServiceID: 7, Status: "problem"})
+	if err == nil {
+		t.Error("expected error when the event is rejected")
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	var tests = []struct {
+		name     string
+		apiURL   string
+		expected string
+	}{
+		{"default", "", "https://events.pagerduty.com/v2/enqueue"},
+		{"stand-in", "http://127.0.0.1:9000", "http://127.0.0.1:9000/v2/enqueue"},
+		{"trailing-slash", "http://127.0.0.1:9000/", "http://127.0.0.1:9000/v2/enqueue"},
+	}
+
+	for _, e := range tests {
+		if got := (Config{APIURL: e.apiURL}).endpoint(); got != e.expected {
+			t.Errorf("%s: expected %s, but got %s", e.name, e.expected, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package slacknotifier posts notifications to a Slack channel through an incoming webhook
+package slacknotifier
+
+import (
+	"context"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+func init() {
+	notifiers.Register("slack", "Slack", &Notifier{})
+}
+
+// colors are the attachment colours for each severity of message
+var colors = map[string]string{
+	"good":    "#28a745",
+	"warning": "#ffc107",
+	"danger":  "#dc3545",
+	"neutral": "#6c757d",
+}
+
+// Config is the per channel configuration for Slack: the incoming webhook that posts to it
+type Config struct {
+	WebhookURL string `json:"webhook_url"`
+}
+
+// Notifier posts notifications to Slack
+type Notifier struct{}
+
+// Payload is the body of an incoming webhook request
+type Payload struct {
+	Text        string       `json:"text"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Attachment is a message attachment, which Slack shows with a coloured bar down its side
+type Attachment struct {
+	Fallback  string  `json:"fallback"`
+	Color     string  `json:"color"`
+	Title     string  `json:"title"`
+	TitleLink string  `json:"title_link,omitempty"`
+	Text      string  `json:"text,omitempty"`
+	Fields    []Field `json:"fields,omitempty"`
+	Footer    string  `json:"footer"`
+	Ts        int64   `json:"ts,omitempty"`
+}
+
+// Field is a short name and value shown in an attachment
+type Field struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Notify posts a message to the channel's webhook
+func (n *Notifier) Notify(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	return notifiers.PostJSON(ctx, cfg.WebhookURL, NewPayload(msg))
+}
+
+// NewPayload builds the webhook request for a message
+func NewPayload(msg notifiers.Message) Payload {
+	a := Attachment{
+		Fallback:  msg.Text,
+		Color:     colors[msg.Severity()],
+		Title:     msg.Subject,
+		TitleLink: msg.URL,
+		Text:      msg.Detail,
+		Footer:    "spectre",
+	}
+	if !msg.Time.IsZero() {
+		a.Ts = msg.Time.Unix()
+	}
+
+	for _, f := range []Field{
+		{"Host", msg.HostName, true},
+		{"Service", msg.ServiceName, true},
+		{"Old Status", msg.OldStatus, true},
+		{"New Status", msg.Status, true},
+	} {
+		if f.Value != "" {
+			a.Fields = append(a.Fields, f)
+		}
+	}
+
+	return Payload{Text: msg.Subject, Attachments: []Attachment{a}}
+}
+
+// Fields describes the settings of a Slack channel
+func (n *Notifier) Fields() []notifiers.Field {
+	return []notifiers.Field{
+		{Name: "webhook_url", Label: "Incoming Webhook URL", Placeholder: "https://hooks.slack.com/services/...", Required: true},
+	}
+}
+
+// Validate checks the webhook url
+func (n *Notifier) Validate(ch models.NotificationChannel) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	return notifiers.ValidURL(cfg.WebhookURL)
+}
@@ -0,0 +1,103 @@
+package slacknotifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+func TestNotify(t *testing.T) {
+	var got Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	msg := notifiers.Message{
+		EventType:   "problem",
+		HostID:      4,
+		HostName:    "web",
+		ServiceName: "HTTP",
+		OldStatus:   "healthy",
+		Status:      "problem",
+		Subject:     "PROBLEM: service HTTP on web",
+		Detail:      "connection refused",
+		Text:        "Service HTTP on web reports a problem: connection refused",
+		URL:         "https://spectre.example.com/admin/host/4",
+		Time:        time.Unix(1700000000, 0),
+	}
+	ch := models.NotificationChannel{Name: "ops", ChannelType: "slack", Config: `{"webhook_url": "` + srv.URL + `"}`}
+
+	err := (&Notifier{}).Notify(context.Background(), ch, msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(got.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment, but got %d", len(got.Attachments))
+	}
+	a := got.Attachments[0]
+	if a.Color != "#dc3545" {
+		t.Errorf("expected problem to be coloured #dc3545, but got %s", a.Color)
+	}
+	if a.TitleLink != msg.URL || a.Text != msg.Detail || a.Ts != 1700000000 {
+		t.Errorf("expected link, detail and time of the message, but got %+v", a)
+	}
+
+	values := make(map[string]string)
+	for _, f := range a.Fields {
+		values[f.Title] = f.Value
+	}
+	for title, expected := range map[string]string{"Host": "web", "Service": "HTTP", "Old Status": "healthy", "New Status": "problem"} {
+		if values[title] != expected {
+			t.Errorf("%s: expected %s, but got %s", title, expected, values[title])
+		}
+	}
+}
+
+func TestNotifyError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("no_team"))
+	}))
+	defer srv.Close()
+
+	ch := models.NotificationChannel{Name: "ops", ChannelType: "slack", Config: `{"webhook_url": "` + srv.URL + `"}`}
+	err := (&Notifier{}).Notify(context.Background(), ch, notifiers.Message{Status: "healthy"})
+	if err == nil {
+		t.Error("expected error when the webhook is rejected")
+	}
+}
+
+func TestColors(t *testing.T) {
+	var tests = []struct {
+		name     string
+		msg      notifiers.Message
+		expected string
+	}{
+		{"healthy", notifiers.Message{EventType: "healthy", Status: "healthy"}, "#28a745"},
+		{"warning", notifiers.Message{EventType: "warning", Status: "warning"}, "#ffc107"},
+		{"unknown", notifiers.Message{EventType: "unknown", Status: "unknown"}, "#6c757d"},
+		{"flapping", notifiers.Message{EventType: "flapping-started", Status: "healthy"}, "#ffc107"},
+	}
+
+	for _, e := range tests {
+		if got := NewPayload(e.msg).Attachments[0].Color; got != e.expected {
+			t.Errorf("%s: expected %s, but got %s", e.name, e.expected, got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package smsnotifier sends notifications by text message through Twilio, using the account set up
+// on the settings page
+package smsnotifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/wtran29/spectre/internal/config"
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+	"github.com/wtran29/spectre/internal/sms"
+)
+
+func init() {
+	notifiers.Register("sms", "Text Message", &Notifier{})
+}
+
+var (
+	mu  sync.RWMutex
+	app *config.AppConfig
+)
+
+// Configure sets the application config that holds the Twilio account preferences
+func Configure(a *config.AppConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	app = a
+}
+
+// Config is the per channel configuration for text messages: the number that receives them
+type Config struct {
+	To string `json:"to"`
+}
+
+// Notifier sends notification text messages
+type Notifier struct{}
+
+// Notify sends the plain text of a message to the channel's number
+func (n *Notifier) Notify(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+
+	mu.RLock()
+	a := app
+	mu.RUnlock()
+
+	if a == nil {
+		return errors.New("text messages are not configured")
+	}
+	return sms.SendTextTwilio(cfg.To, msg.Text, a)
+}
+
+// Fields describes the settings of a text message channel
+func (n *Notifier) Fields() []notifiers.Field {
+	return []notifiers.Field{
+		{Name: "to", Label: "Number that receives messages", Placeholder: "+15555550123", Required: true},
+	}
+}
@@ -0,0 +1,118 @@
+// Package teamsnotifier posts notifications to a Microsoft Teams channel through an incoming
+// webhook, as Adaptive Cards
+package teamsnotifier
+
+import (
+	"context"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+func init() {
+	notifiers.Register("teams", "Microsoft Teams", &Notifier{})
+}
+
+// styles are the container styles for each severity of message
+var styles = map[string]string{
+	"good":    "good",
+	"warning": "warning",
+	"danger":  "attention",
+	"neutral": "emphasis",
+}
+
+// Config is the per channel configuration for Teams: the incoming webhook that posts to it
+type Config struct {
+	WebhookURL string `json:"webhook_url"`
+}
+
+// Notifier posts notifications to Teams
+type Notifier struct{}
+
+// Notify posts a message to the channel's webhook
+func (n *Notifier) Notify(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	return notifiers.PostJSON(ctx, cfg.WebhookURL, NewPayload(msg))
+}
+
+// NewPayload builds the webhook request for a message: a message with a single Adaptive Card,
+// whose heading is styled by the severity of the message
+func NewPayload(msg notifiers.Message) map[string]interface{} {
+	var facts []map[string]string
+	for _, f := range [][2]string{
+		{"Host", msg.HostName},
+		{"Service", msg.ServiceName},
+		{"Old Status", msg.OldStatus},
+		{"New Status", msg.Status},
+	} {
+		if f[1] != "" {
+			facts = append(facts, map[string]string{"title": f[0], "value": f[1]})
+		}
+	}
+
+	body := []interface{}{
+		map[string]interface{}{
+			"type":  "Container",
+			"style": styles[msg.Severity()],
+			"bleed": true,
+			"items": []interface{}{
+				map[string]interface{}{
+					"type":   "TextBlock",
+					"text":   msg.Subject,
+					"weight": "Bolder",
+					"size":   "Medium",
+					"wrap":   true,
+				},
+			},
+		},
+	}
+	if len(facts) > 0 {
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+	if msg.Detail != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": msg.Detail, "wrap": true})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]string{"width": "Full"},
+		"body":    body,
+	}
+	if msg.URL != "" {
+		card["actions"] = []interface{}{
+			map[string]string{"type": "Action.OpenUrl", "title": "View Host", "url": msg.URL},
+		}
+	}
+
+	return map[string]interface{}{
+		"type":    "message",
+		"summary": msg.Subject,
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     card,
+			},
+		},
+	}
+}
+
+// Fields describes the settings of a Teams channel
+func (n *Notifier) Fields() []notifiers.Field {
+	return []notifiers.Field{
+		{Name: "webhook_url", Label: "Incoming Webhook URL", Placeholder: "https://example.webhook.office.com/...", Required: true},
+	}
+}
+
+// Validate checks the webhook url
+func (n *Notifier) Validate(ch models.NotificationChannel) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	return notifiers.ValidURL(cfg.WebhookURL)
+}
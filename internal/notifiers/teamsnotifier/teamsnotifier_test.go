@@ -0,0 +1,109 @@
+package teamsnotifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+// card is the part of a Teams message the tests look at
+type card struct {
+	Attachments []struct {
+		ContentType string `json:"contentType"`
+		Content     struct {
+			Type string `json:"type"`
+			Body []struct {
+				Type  string `json:"type"`
+				Style string `json:"style"`
+				Text  string `json:"text"`
+				Facts []struct {
+					Title string `json:"title"`
+					Value string `json:"value"`
+				} `json:"facts"`
+			} `json:"body"`
+			Actions []struct {
+				URL string `json:"url"`
+			} `json:"actions"`
+		} `json:"content"`
+	} `json:"attachments"`
+}
+
+func TestNotify(t *testing.T) {
+	var got card
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("1"))
+	}))
+	defer srv.Close()
+
+	msg := notifiers.Message{
+		EventType:   "healthy",
+		HostName:    "db",
+		ServiceName: "TCP",
+		OldStatus:   "problem",
+		Status:      "healthy",
+		Subject:     "HEALTHY: service TCP on db",
+		Detail:      "connected in 3ms",
+		URL:         "https://spectre.example.com/admin/host/2",
+	}
+	ch := models.NotificationChannel{Name: "ops", ChannelType: "teams", Config: `{"webhook_url": "` + srv.URL + `"}`}
+
+	err := (&Notifier{}).Notify(context.Background(), ch, msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(got.Attachments) != 1 || got.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("expected one adaptive card, but got %+v", got)
+	}
+	c := got.Attachments[0].Content
+	if c.Type != "AdaptiveCard" || len(c.Body) != 3 {
+		t.Fatalf("expected adaptive card with heading, facts and detail, but got %+v", c)
+	}
+	if c.Body[0].Style != "good" {
+		t.Errorf("expected healthy heading to be styled good, but got %s", c.Body[0].Style)
+	}
+
+	var facts []string
+	for _, f := range c.Body[1].Facts {
+		facts = append(facts, f.Title+"="+f.Value)
+	}
+	if strings.Join(facts, ",") != "Host=db,Service=TCP,Old Status=problem,New Status=healthy" {
+		t.Errorf("unexpected facts %v", facts)
+	}
+	if c.Body[2].Text != msg.Detail {
+		t.Errorf("expected detail %s, but got %s", msg.Detail, c.Body[2].Text)
+	}
+	if len(c.Actions) != 1 || c.Actions[0].URL != msg.URL {
+		t.Errorf("expected link to %s, but got %+v", msg.URL, c.Actions)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{"https", `{"webhook_url": "https://example.webhook.office.com/webhookb2/abc"}`, false},
+		{"local", `{"webhook_url": "http://127.0.0.1:8080/hook"}`, false},
+		{"no-scheme", `{"webhook_url": "example.webhook.office.com/webhookb2/abc"}`, true},
+		{"ftp", `{"webhook_url": "ftp://example.com/"}`, true},
+	}
+
+	for _, e := range tests {
+		err := (&Notifier{}).Validate(models.NotificationChannel{Config: e.config})
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
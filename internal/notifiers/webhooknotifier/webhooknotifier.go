@@ -0,0 +1,380 @@
+// Package webhooknotifier posts notifications as JSON to a url of the user's choosing, so status
+// changes can drive other automation.
+//
+// Unless the channel has a template, the body is the Payload of the notification:
+//
+//	{
+//	  "event": {
+//	    "event_type": "problem",
+//	    "host_service_id": 7,
+//	    "host_id": 2,
+//	    "service_name": "HTTP",
+//	    "host_name": "web",
+//	    "message": "connection refused",
+//	    "maintenance": 0,
+//	    "created_at": "2026-10-18T09:14:00Z"
+//	  },
+//	  "host": {"id": 2, "name": "web", "url": "https://spectre.example.com/admin/host/2"},
+//	  "service": {"id": 7, "name": "HTTP", "old_status": "healthy", "status": "problem"},
+//	  "subject": "PROBLEM: service HTTP on web"
+//	}
+//
+// A template is a Go text/template executed with the Payload, whose output must be JSON; its json
+// function quotes a value, as in {"text": {{json .Subject}}}.
+//
+// When the channel has a secret, each request is signed with it: the X-Spectre-Signature header
+// holds "sha256=" and the hex HMAC-SHA256 of the body. Without a secret the header is left out, as
+// a signature anyone can make proves nothing. Requests that fail with a network error, a 429 or
+// a 5xx response are retried, waiting twice as long before each retry, and every delivery is
+// recorded so that it can be looked at and replayed.
+package webhooknotifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+func init() {
+	notifiers.Register("webhook", "Webhook", &Notifier{})
+}
+
+// SignatureHeader is the header that carries the signature of a request's body
+const SignatureHeader = "X-Spectre-Signature"
+
+const (
+	// defaultRetries is how many times a failed request is retried when the channel does not say
+	defaultRetries = 3
+	// maxRetries caps the retries a channel can ask for
+	maxRetries = 10
+	// attemptTimeout is how long each request may take
+	attemptTimeout = 10 * time.Second
+	// maxErrorBytes caps how much of an error response is kept in the delivery log
+	maxErrorBytes = 512
+)
+
+// backoff is how long to wait before the first retry; each retry after it waits twice as long
+var backoff = time.Second
+
+// Store records deliveries
+type Store interface {
+	InsertWebhookDelivery(d models.WebhookDelivery) (int, error)
+}
+
+var (
+	mu    sync.RWMutex
+	store Store
+)
+
+// Configure sets where deliveries are recorded. Until it is called, they are not recorded.
+func Configure(s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	store = s
+}
+
+// Config is the per channel configuration for a webhook
+type Config struct {
+	URL      string `json:"url"`
+	Secret   string `json:"secret"`
+	Template string `json:"template"`
+	Retries  string `json:"retries"`
+}
+
+// retries returns how many times a failed request is retried
+func (c Config) retries() int {
+	n, err := strconv.Atoi(strings.TrimSpace(c.Retries))
+	if err != nil || n < 0 {
+		return defaultRetries
+	}
+	if n > maxRetries {
+		return maxRetries
+	}
+	return n
+}
+
+// Payload is the body posted for a notification, and what a template is executed with
+type Payload struct {
+	Event   Event   `json:"event"`
+	Host    Host    `json:"host"`
+	Service Service `json:"service"`
+	Subject string  `json:"subject"`
+}
+
+// Event holds the fields of the event recorded for a notification
+type Event struct {
+	EventType     string    `json:"event_type"`
+	HostServiceID int       `json:"host_service_id"`
+	HostID        int       `json:"host_id"`
+	ServiceName   string    `json:"service_name"`
+	HostName      string    `json:"host_name"`
+	Message       string    `json:"message"`
+	Maintenance   int       `json:"maintenance"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Host describes the host of a notification
+type Host struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Service describes the host service of a notification and its change of status
+type Service struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	OldStatus string `json:"old_status"`
+	Status    string `json:"status"`
+}
+
+// NewPayload builds the payload for a message. Notifications are not sent for services in
+// maintenance, so the event's maintenance flag is always 0.
+func NewPayload(msg notifiers.Message) Payload {
+	return Payload{
+		Event: Event{
+			EventType:     msg.EventType,
+			HostServiceID: msg.HostServiceID,
+			HostID:        msg.HostID,
+			ServiceName:   msg.ServiceName,
+			HostName:      msg.HostName,
+			Message:       msg.Detail,
+			CreatedAt:     msg.Time.UTC(),
+		},
+		Host: Host{
+			ID:   msg.HostID,
+			Name: msg.HostName,
+			URL:  msg.URL,
+		},
+		Service: Service{
+			ID:        msg.HostServiceID,
+			Name:      msg.ServiceName,
+			OldStatus: msg.OldStatus,
+			Status:    msg.Status,
+		},
+		Subject: msg.Subject,
+	}
+}
+
+// templateFuncs are the functions available to templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Render returns the body to post for a payload: the output of the template, or the payload as
+// JSON when there is no template
+func Render(tmpl string, p Payload) ([]byte, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		return json.Marshal(p)
+	}
+
+	t, err := template.New("webhook").Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("invalid template: output is not JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign returns the signature of a body made with a secret, as sent in SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notifier posts notifications to webhooks
+type Notifier struct{}
+
+// Notify posts a message to the channel's url, retrying if it fails, and records the delivery
+func (n *Notifier) Notify(ctx context.Context, ch models.NotificationChannel, msg notifiers.Message) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+
+	body, err := Render(cfg.Template, NewPayload(msg))
+	if err != nil {
+		return err
+	}
+
+	return deliver(ctx, ch, cfg, models.WebhookDelivery{
+		NotificationChannelID: ch.ID,
+		EventType:             msg.EventType,
+		HostServiceID:         msg.HostServiceID,
+		Payload:               string(body),
+	})
+}
+
+// Replay posts the payload of an earlier delivery again, signed with the channel's current secret,
+// and records it as a new delivery. It is asked for by someone waiting on the result, so there is
+// a single try and no retries.
+func Replay(ctx context.Context, ch models.NotificationChannel, d models.WebhookDelivery) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	cfg.Retries = "0"
+
+	return deliver(ctx, ch, cfg, models.WebhookDelivery{
+		NotificationChannelID: ch.ID,
+		EventType:             d.EventType,
+		HostServiceID:         d.HostServiceID,
+		Payload:               d.Payload,
+	})
+}
+
+// deliver posts the payload of a delivery until it succeeds or the retries run out, then records
+// how it went
+func deliver(ctx context.Context, ch models.NotificationChannel, cfg Config, d models.WebhookDelivery) error {
+	var err error
+	wait := backoff
+	for d.Attempts = 1; ; d.Attempts++ {
+		var retry bool
+		d.StatusCode, retry, err = post(ctx, cfg, []byte(d.Payload), d.EventType)
+		if err == nil || !retry || d.Attempts > cfg.retries() {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("%s (gave up retrying: %s)", err, ctx.Err())
+		case <-time.After(wait):
+			wait *= 2
+			continue
+		}
+		break
+	}
+
+	if err == nil {
+		d.Delivered = 1
+	} else {
+		d.Error = err.Error()
+	}
+	record(d)
+	return err
+}
+
+// post makes a single request, and reports its status code and whether a failure is worth
+// retrying
+func post(ctx context.Context, cfg Config, body []byte, eventType string) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "spectre")
+	req.Header.Set("X-Spectre-Event", eventType)
+	if cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(cfg.Secret, body))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return resp.StatusCode, retry, fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, bytes.TrimSpace(b))
+	}
+	return resp.StatusCode, false, nil
+}
+
+// record saves a delivery in the store, if there is one
+func record(d models.WebhookDelivery) {
+	mu.RLock()
+	s := store
+	mu.RUnlock()
+
+	if s == nil {
+		return
+	}
+	if _, err := s.InsertWebhookDelivery(d); err != nil {
+		log.Println(err)
+	}
+}
+
+// Fields describes the settings of a webhook channel
+func (n *Notifier) Fields() []notifiers.Field {
+	return []notifiers.Field{
+		{Name: "url", Label: "URL", Placeholder: "https://automation.example.com/hooks/spectre", Required: true},
+		{Name: "secret", Label: "Secret", Help: "Requests are signed with this secret in the X-Spectre-Signature header: sha256= and the hex HMAC-SHA256 of the body. Leave empty to send requests unsigned."},
+		{Name: "retries", Label: "Retries", Placeholder: strconv.Itoa(defaultRetries), Help: "How many times a failed request is retried, waiting 1s, 2s, 4s... between tries."},
+		{Name: "template", Label: "Payload Template", Multiline: true,
+			Placeholder: `{"text": {{json .Subject}}, "status": {{json .Service.Status}}}`,
+			Help:        "A Go template that produces the JSON to post, with .Event, .Host, .Service and .Subject, and json to quote a value. Leave empty to post the whole payload."},
+	}
+}
+
+// Validate checks the url, the retries and that the template produces JSON
+func (n *Notifier) Validate(ch models.NotificationChannel) error {
+	var cfg Config
+	if err := notifiers.DecodeConfig(ch, &cfg); err != nil {
+		return err
+	}
+	if err := notifiers.ValidURL(cfg.URL); err != nil {
+		return err
+	}
+	if r := strings.TrimSpace(cfg.Retries); r != "" {
+		if n, err := strconv.Atoi(r); err != nil || n < 0 || n > maxRetries {
+			return fmt.Errorf("retries must be between 0 and %d", maxRetries)
+		}
+	}
+
+	sample := notifiers.Message{
+		EventType:     "problem",
+		HostID:        1,
+		HostServiceID: 1,
+		HostName:      "host",
+		ServiceName:   "service",
+		OldStatus:     "healthy",
+		Status:        "problem",
+		Subject:       "PROBLEM: service service on host",
+		Detail:        "message",
+		Time:          time.Now(),
+	}
+	_, err := Render(cfg.Template, NewPayload(sample))
+	return err
+}
+
+// Timeout allows for every try and the waits between them
+func (n *Notifier) Timeout(ch models.NotificationChannel) time.Duration {
+	var cfg Config
+	_ = notifiers.DecodeConfig(ch, &cfg)
+
+	retries := cfg.retries()
+	wait := backoff * time.Duration((1<<retries)-1)
+	return time.Duration(retries+1)*attemptTimeout + wait
+}
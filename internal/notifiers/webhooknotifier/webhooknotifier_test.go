@@ -0,0 +1,274 @@
+package webhooknotifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+	"github.com/wtran29/spectre/internal/notifiers"
+)
+
+// memoryStore keeps deliveries in memory
+type memoryStore struct {
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+}
+
+func (s *memoryStore) InsertWebhookDelivery(d models.WebhookDelivery) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, d)
+	return len(s.deliveries), nil
+}
+
+func (s *memoryStore) last() models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deliveries[len(s.deliveries)-1]
+}
+
+var testMessage = notifiers.Message{
+	EventType:     "problem",
+	HostID:        2,
+	HostServiceID: 7,
+	HostName:      "web",
+	ServiceName:   "HTTP",
+	OldStatus:     "healthy",
+	Status:        "problem",
+	Subject:       "PROBLEM: service HTTP on web",
+	Detail:        "connection refused",
+	URL:           "https://spectre.example.com/admin/host/2",
+	Time:          time.Date(2026, 10, 18, 9, 14, 0, 0, time.UTC),
+}
+
+func channel(config string) models.NotificationChannel {
+	return models.NotificationChannel{ID: 3, Name: "automation", ChannelType: "webhook", Config: config}
+}
+
+func TestNotify(t *testing.T) {
+	store := &memoryStore{}
+	Configure(store)
+	defer Configure(nil)
+
+	var body []byte
+	var signature, event string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		event = r.Header.Get("X-Spectre-Event")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	err := (&Notifier{}).Notify(context.Background(), channel(`{"url": "`+srv.URL+`", "secret": "s3cret"}`), testMessage)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if signature != Sign("s3cret", body) {
+		t.Errorf("expected signature %s, but got %s", Sign("s3cret", body), signature)
+	}
+	if event != "problem" {
+		t.Errorf("expected event header problem, but got %s", event)
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("payload is not JSON: %s", err)
+	}
+	if p.Event.HostServiceID != 7 || p.Event.Message != "connection refused" || p.Host.URL != testMessage.URL ||
+		p.Service.OldStatus != "healthy" || p.Service.Status != "problem" || !p.Event.CreatedAt.Equal(testMessage.Time) {
+		t.Errorf("unexpected payload %+v", p)
+	}
+
+	d := store.last()
+	if d.Delivered != 1 || d.Attempts != 1 || d.StatusCode != http.StatusNoContent || d.NotificationChannelID != 3 || d.Payload != string(body) {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+}
+
+func TestNotifyWithoutSecret(t *testing.T) {
+	signed := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, signed = r.Header[SignatureHeader]
+	}))
+	defer srv.Close()
+
+	err := (&Notifier{}).Notify(context.Background(), channel(`{"url": "`+srv.URL+`"}`), testMessage)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if signed {
+		t.Error("expected no signature header without a secret")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// from the HMAC-SHA256 test vectors of RFC 4231, test case 2
+	expected := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got := Sign("Jefe", []byte("what do ya want for nothing?")); got != expected {
+		t.Errorf("expected %s, but got %s", expected, got)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	backoff = time.Millisecond
+	defer func() { backoff = time.Second }()
+
+	store := &memoryStore{}
+	Configure(store)
+	defer Configure(nil)
+
+	var tests = []struct {
+		name              string
+		retries           string
+		failures          int
+		failWith          int
+		expectError       bool
+		expectedAttempts  int
+		expectedDelivered int
+	}{
+		{"succeeds-after-retries", "3", 2, http.StatusServiceUnavailable, false, 3, 1},
+		{"retries-run-out", "2", 5, http.StatusInternalServerError, true, 3, 0},
+		{"rate-limited", "1", 1, http.StatusTooManyRequests, false, 2, 1},
+		{"not-retried", "3", 1, http.StatusBadRequest, true, 1, 0},
+		{"no-retries", "0", 1, http.StatusBadGateway, true, 1, 0},
+	}
+
+	for _, e := range tests {
+		var mu sync.Mutex
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls <= e.failures {
+				w.WriteHeader(e.failWith)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		err := (&Notifier{}).Notify(context.Background(), channel(`{"url": "`+srv.URL+`", "retries": "`+e.retries+`"}`), testMessage)
+		srv.Close()
+
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+		d := store.last()
+		if d.Attempts != e.expectedAttempts || d.Delivered != e.expectedDelivered {
+			t.Errorf("%s: expected %d attempts and delivered %d, but got %d and %d", e.name, e.expectedAttempts, e.expectedDelivered, d.Attempts, d.Delivered)
+		}
+		if e.expectError && d.Error == "" {
+			t.Errorf("%s: expected error in delivery log", e.name)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	store := &memoryStore{}
+	Configure(store)
+	defer Configure(nil)
+
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer srv.Close()
+
+	old := models.WebhookDelivery{ID: 9, NotificationChannelID: 3, EventType: "warning", HostServiceID: 7, Payload: `{"replayed": true}`, Error: "timeout"}
+	err := Replay(context.Background(), channel(`{"url": "`+srv.URL+`", "secret": "new"}`), old)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if string(body) != old.Payload || signature != Sign("new", body) {
+		t.Errorf("expected the old payload signed with the new secret, but got %s signed %s", body, signature)
+	}
+	if d := store.last(); d.Delivered != 1 || d.EventType != "warning" || d.HostServiceID != 7 || d.Error != "" {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+}
+
+func TestReplayDoesNotRetry(t *testing.T) {
+	backoff = time.Millisecond
+	defer func() { backoff = time.Second }()
+
+	store := &memoryStore{}
+	Configure(store)
+	defer Configure(nil)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	old := models.WebhookDelivery{ID: 9, NotificationChannelID: 3, EventType: "warning", Payload: `{}`}
+	err := Replay(context.Background(), channel(`{"url": "`+srv.URL+`", "retries": "5"}`), old)
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if d := store.last(); calls != 1 || d.Attempts != 1 {
+		t.Errorf("expected a single attempt, but got %d calls and %d attempts", calls, d.Attempts)
+	}
+}
+
+func TestRender(t *testing.T) {
+	var tests = []struct {
+		name        string
+		template    string
+		expected    string
+		expectError bool
+	}{
+		{"template", `{"text": {{json .Subject}}, "status": {{json .Service.Status}}, "id": {{.Event.HostServiceID}}}`,
+			`{"text": "PROBLEM: service HTTP on web", "status": "problem", "id": 7}`, false},
+		{"quotes", `{"message": {{json .Event.Message}}}`, `{"message": "say \"hi\""}`, false},
+		{"not-json", `text: {{.Subject}}`, "", true},
+		{"bad-syntax", `{"text": {{json .Subject}`, "", true},
+		{"unknown-field", `{"text": {{json .Nope}}}`, "", true},
+	}
+
+	msg := testMessage
+	msg.Detail = `say "hi"`
+	for _, e := range tests {
+		got, err := Render(e.template, NewPayload(msg))
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+		if err == nil && string(got) != e.expected {
+			t.Errorf("%s: expected %s, but got %s", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{"url-only", `{"url": "https://example.com/hook"}`, false},
+		{"template", `{"url": "https://example.com/hook", "template": "{\"s\": {{json .Subject}}}", "retries": "5"}`, false},
+		{"bad-url", `{"url": "example.com/hook"}`, true},
+		{"bad-template", `{"url": "https://example.com/hook", "template": "{{.Subject}}"}`, true},
+		{"bad-retries", `{"url": "https://example.com/hook", "retries": "eleven"}`, true},
+		{"too-many-retries", `{"url": "https://example.com/hook", "retries": "11"}`, true},
+	}
+
+	for _, e := range tests {
+		err := (&Notifier{}).Validate(channel(e.config))
+		if e.expectError != (err != nil) {
+			t.Errorf("%s: expected error %t, but got %v", e.name, e.expectError, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// AllAgents returns all remote agents
+func (m *postgresDBRepo) AllAgents() ([]models.Agent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT id, name, token_hash, last_seen, active, created_at, updated_at FROM agents ORDER BY name`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []models.Agent
+
+	for rows.Next() {
+		var a models.Agent
+		err = rows.Scan(&a.ID, &a.Name, &a.TokenHash, &a.LastSeen, &a.Active, &a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return agents, nil
+}
+
+// InsertAgent inserts a remote agent into the database
+func (m *postgresDBRepo) InsertAgent(a models.Agent) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `INSERT INTO agents (name, token_hash, active, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5) returning id`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, query,
+		a.Name,
+		a.TokenHash,
+		a.Active,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// GetAgentByTokenHash returns the agent whose token hashes to hash
+func (m *postgresDBRepo) GetAgentByTokenHash(hash string) (models.Agent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT id, name, token_hash, last_seen, active, created_at, updated_at FROM agents WHERE token_hash = $1`
+
+	var a models.Agent
+	row := m.DB.QueryRowContext(ctx, query, hash)
+	err := row.Scan(&a.ID, &a.Name, &a.TokenHash, &a.LastSeen, &a.Active, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return a, err
+	}
+
+	return a, nil
+}
+
+// UpdateAgentLastSeen records that an agent has just called in
+func (m *postgresDBRepo) UpdateAgentLastSeen(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE agents SET last_seen = $1 WHERE id = $2`
+
+	_, err := m.DB.ExecContext(ctx, stmt, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAgent deletes an agent, handing the checks of its hosts back to the server
+func (m *postgresDBRepo) DeleteAgent(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE hosts SET agent_id = 0, updated_at = $1 WHERE agent_id = $2`, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM agents WHERE id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetServicesForAgent returns the active host services that an agent should check: those of the
+// active hosts assigned to it. Heartbeat services are left to the server, which receives the pings.
+func (m *postgresDBRepo) GetServicesForAgent(agentID int) ([]models.HostService, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.last_check,
+				hs.status, hs.created_at, hs.updated_at, s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, h.host_name, hs.last_message, hs.config, hs.heartbeat_token, hs.last_heartbeat, hs.heartbeat_ok, hs.heartbeat_message, hs.check_state, hs.attempts, hs.attempt_status, hs.status_history, hs.flap_score, hs.flapping, hs.activated_at
+			FROM host_services hs
+			LEFT JOIN services s ON (hs.service_id = s.id)
+			LEFT JOIN hosts h ON (h.id = hs.host_id)
+			WHERE h.active = 1 AND hs.active = 1 AND h.agent_id = $1 AND s.service_name <> 'Heartbeat'
+			ORDER BY hs.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []models.HostService
+
+	for rows.Next() {
+		var h models.HostService
+		err := rows.Scan(
+			&h.ID,
+			&h.HostID,
+			&h.ServiceID,
+			&h.Active,
+			&h.ScheduleNumber,
+			&h.ScheduleUnit,
+			&h.LastCheck,
+			&h.Status,
+			&h.CreatedAt,
+			&h.UpdatedAt,
+			&h.Service.ID,
+			&h.Service.ServiceName,
+			&h.Service.Active,
+			&h.Service.Icon,
+			&h.Service.CreatedAt,
+			&h.Service.UpdatedAt,
+			&h.HostName,
+			&h.LastMessage,
+			&h.Config,
+			&h.HeartbeatToken,
+			&h.LastHeartbeat,
+			&h.HeartbeatOK,
+			&h.HeartbeatMessage,
+			&h.CheckState,
+			&h.Attempts,
+			&h.AttemptStatus,
+			&h.StatusHistory,
+			&h.FlapScore,
+			&h.Flapping,
+			&h.ActivatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return services, err
+		}
+		services = append(services, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return services, nil
+}
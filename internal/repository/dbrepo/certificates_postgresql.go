@@ -0,0 +1,147 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// GetCertificates returns the certificates in the inventory that match filter, soonest
+// expiring first
+func (m *postgresDBRepo) GetCertificates(filter models.CertificateFilter) ([]models.Certificate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		SELECT c.id, c.host_service_id, c.source, c.subject, c.issuer, c.serial_number, c.thumbprint,
+			c.dns_names, c.not_after, c.key_mismatch, h.id, h.host_name, s.service_name,
+			c.created_at, c.updated_at
+		FROM certificates c
+			LEFT JOIN host_services hs on (c.host_service_id = hs.id)
+			LEFT JOIN hosts h on (hs.host_id = h.id)
+			LEFT JOIN services s on (hs.service_id = s.id)
+		WHERE ($1 = '' OR c.issuer = $1)
+			AND ($2::timestamp IS NULL OR c.not_after < $2)
+		ORDER BY c.not_after, h.host_name, c.source`
+
+	var expiresBefore interface{}
+	if !filter.ExpiresBefore.IsZero() {
+		expiresBefore = filter.ExpiresBefore
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, filter.Issuer, expiresBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certs []models.Certificate
+
+	for rows.Next() {
+		var c models.Certificate
+		err = rows.Scan(
+			&c.ID,
+			&c.HostServiceID,
+			&c.Source,
+			&c.Subject,
+			&c.Issuer,
+			&c.SerialNumber,
+			&c.Thumbprint,
+			&c.DNSNames,
+			&c.NotAfter,
+			&c.KeyMismatch,
+			&c.HostID,
+			&c.HostName,
+			&c.ServiceName,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return certs, nil
+}
+
+// GetCertificateIssuers returns the issuers of the certificates in the inventory
+func (m *postgresDBRepo) GetCertificateIssuers() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, `SELECT DISTINCT issuer FROM certificates ORDER BY issuer`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issuers []string
+	for rows.Next() {
+		var issuer string
+		if err = rows.Scan(&issuer); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		issuers = append(issuers, issuer)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return issuers, nil
+}
+
+// ReplaceCertificates replaces the certificates in the inventory for a host service with the
+// ones its latest check found
+func (m *postgresDBRepo) ReplaceCertificates(hostServiceID int, certs []models.Certificate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM certificates WHERE host_service_id = $1`, hostServiceID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO certificates (host_service_id, source, subject, issuer, serial_number,
+				thumbprint, dns_names, not_after, key_mismatch, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`
+
+	now := time.Now()
+	for _, c := range certs {
+		_, err = tx.ExecContext(ctx, stmt,
+			hostServiceID,
+			c.Source,
+			c.Subject,
+			c.Issuer,
+			c.SerialNumber,
+			c.Thumbprint,
+			c.DNSNames,
+			c.NotAfter,
+			c.KeyMismatch,
+			now,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,101 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// AllDependencies returns all host dependencies, with the names of the hosts and services involved
+func (m *postgresDBRepo) AllDependencies() ([]models.Dependency, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT d.id, d.host_id, d.host_service_id, d.parent_host_id, d.parent_host_service_id,
+				h.host_name, coalesce(s.service_name, ''), ph.host_name, coalesce(ps.service_name, ''),
+				d.created_at, d.updated_at
+			FROM dependencies d
+			LEFT JOIN hosts h ON (h.id = d.host_id)
+			LEFT JOIN host_services hs ON (hs.id = d.host_service_id)
+			LEFT JOIN services s ON (s.id = hs.service_id)
+			LEFT JOIN hosts ph ON (ph.id = d.parent_host_id)
+			LEFT JOIN host_services phs ON (phs.id = d.parent_host_service_id)
+			LEFT JOIN services ps ON (ps.id = phs.service_id)
+			ORDER BY h.host_name, d.id`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []models.Dependency
+	for rows.Next() {
+		var d models.Dependency
+		err = rows.Scan(
+			&d.ID,
+			&d.HostID,
+			&d.HostServiceID,
+			&d.ParentHostID,
+			&d.ParentHostServiceID,
+			&d.HostName,
+			&d.ServiceName,
+			&d.ParentHostName,
+			&d.ParentServiceName,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return deps, nil
+}
+
+// InsertDependency inserts a host dependency into the database
+func (m *postgresDBRepo) InsertDependency(d models.Dependency) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `INSERT INTO dependencies (host_id, host_service_id, parent_host_id, parent_host_service_id, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6) returning id`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, query,
+		d.HostID,
+		d.HostServiceID,
+		d.ParentHostID,
+		d.ParentHostServiceID,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// DeleteDependency deletes a host dependency
+func (m *postgresDBRepo) DeleteDependency(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM dependencies WHERE id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
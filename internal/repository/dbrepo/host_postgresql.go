@@ -13,8 +13,8 @@ func (m *postgresDBRepo) InsertHost(h models.Host) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := `INSERT INTO hosts (host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id`
+	query := `INSERT INTO hosts (host_name, canonical_name, url, ip, ipv6, location, os, active, agent_id, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id`
 
 	var newID int
 	// for postgres you have to scan the id after calling QueryRowContext
@@ -27,6 +27,7 @@ func (m *postgresDBRepo) InsertHost(h models.Host) (int, error) {
 		h.Location,
 		h.OS,
 		h.Active,
+		h.AgentID,
 		time.Now(),
 		time.Now(),
 	).Scan(&newID)
@@ -70,7 +71,7 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := `SELECT id, host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at
+	query := `SELECT id, host_name, canonical_name, url, ip, ipv6, location, os, active, agent_id, created_at, updated_at
 				FROM hosts where id = $1`
 
 	row := m.DB.QueryRowContext(ctx, query, id)
@@ -87,6 +88,7 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 		&h.Location,
 		&h.OS,
 		&h.Active,
+		&h.AgentID,
 		&h.CreatedAt,
 		&h.UpdatedAt,
 	)
@@ -98,7 +100,7 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 	// get all services for host
 	query = `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
 				hs.last_check, hs.status, hs.created_at, hs.updated_at,
-				s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, hs.last_message
+				s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, hs.last_message, hs.config, hs.heartbeat_token, hs.last_heartbeat, hs.heartbeat_ok, hs.heartbeat_message, hs.check_state, hs.attempts, hs.attempt_status, hs.status_history, hs.flap_score, hs.flapping, hs.activated_at
 			FROM host_services hs 
 			LEFT JOIN services s on (s.id = hs.service_id) 
 			WHERE host_id = $1
@@ -131,6 +133,18 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 			&hs.Service.CreatedAt,
 			&hs.Service.UpdatedAt,
 			&hs.LastMessage,
+			&hs.Config,
+			&hs.HeartbeatToken,
+			&hs.LastHeartbeat,
+			&hs.HeartbeatOK,
+			&hs.HeartbeatMessage,
+			&hs.CheckState,
+			&hs.Attempts,
+			&hs.AttemptStatus,
+			&hs.StatusHistory,
+			&hs.FlapScore,
+			&hs.Flapping,
+			&hs.ActivatedAt,
 		)
 		if err != nil {
 			return h, err
@@ -149,7 +163,7 @@ func (m *postgresDBRepo) UpdateHost(h models.Host) error {
 	defer cancel()
 
 	stmt := `UPDATE hosts SET host_name = $1, canonical_name = $2, url = $3, ip = $4, ipv6 = $5, location = $6, os = $7,
-				active = $8, updated_at = $9 WHERE id = $10`
+				active = $8, agent_id = $9, updated_at = $10 WHERE id = $11`
 
 	_, err := m.DB.ExecContext(ctx, stmt,
 		h.HostName,
@@ -160,6 +174,7 @@ func (m *postgresDBRepo) UpdateHost(h models.Host) error {
 		h.Location,
 		h.OS,
 		h.Active,
+		h.AgentID,
 		time.Now(),
 		h.ID,
 	)
@@ -171,29 +186,37 @@ func (m *postgresDBRepo) UpdateHost(h models.Host) error {
 	return nil
 }
 
-func (m *postgresDBRepo) GetAllServiceStatusCounts() (int, int, int, int, error) {
+// GetAllServiceStatusCounts returns the number of active host services in each status. Flapping
+// services are only counted as flapping, whatever their latest status.
+func (m *postgresDBRepo) GetAllServiceStatusCounts() (models.ServiceStatusCounts, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := `SELECT (SELECT count(id) FROM host_services WHERE active = 1 AND status = 'pending') AS pending,
-				(SELECT count(id) FROM host_services WHERE active = 1 AND status = 'healthy') AS healthy,
-				(SELECT count(id) FROM host_services WHERE active = 1 AND status = 'warning') AS warning,
-				(SELECT count(id) FROM host_services WHERE active = 1 AND status = 'problem') AS problem
+	query := `SELECT (SELECT count(id) FROM host_services WHERE active = 1 AND flapping = 0 AND status = 'pending') AS pending,
+				(SELECT count(id) FROM host_services WHERE active = 1 AND flapping = 0 AND status = 'healthy') AS healthy,
+				(SELECT count(id) FROM host_services WHERE active = 1 AND flapping = 0 AND status = 'warning') AS warning,
+				(SELECT count(id) FROM host_services WHERE active = 1 AND flapping = 0 AND status = 'problem') AS problem,
+				(SELECT count(id) FROM host_services WHERE active = 1 AND flapping = 0 AND status = 'unknown') AS unknown,
+				(SELECT count(id) FROM host_services WHERE active = 1 AND flapping = 0 AND status = 'unreachable') AS unreachable,
+				(SELECT count(id) FROM host_services WHERE active = 1 AND flapping = 1) AS flapping
 	`
 
-	var pending, healthy, warning, problem int
+	var c models.ServiceStatusCounts
 
 	row := m.DB.QueryRowContext(ctx, query)
 	err := row.Scan(
-		&pending,
-		&healthy,
-		&warning,
-		&problem,
+		&c.Pending,
+		&c.Healthy,
+		&c.Warning,
+		&c.Problem,
+		&c.Unknown,
+		&c.Unreachable,
+		&c.Flapping,
 	)
 	if err != nil {
-		return 0, 0, 0, 0, err
+		return c, err
 	}
-	return pending, healthy, warning, problem, nil
+	return c, nil
 
 }
 
@@ -201,7 +224,7 @@ func (m *postgresDBRepo) AllHosts() ([]models.Host, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	query := `SELECT id, host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at
+	query := `SELECT id, host_name, canonical_name, url, ip, ipv6, location, os, active, agent_id, created_at, updated_at
 				FROM hosts ORDER BY host_name`
 
 	rows, err := m.DB.QueryContext(ctx, query)
@@ -224,6 +247,7 @@ func (m *postgresDBRepo) AllHosts() ([]models.Host, error) {
 			&h.Location,
 			&h.OS,
 			&h.Active,
+			&h.AgentID,
 			&h.CreatedAt,
 			&h.UpdatedAt,
 		)
@@ -235,7 +259,7 @@ func (m *postgresDBRepo) AllHosts() ([]models.Host, error) {
 		// get all services for host
 		serviceQuery := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
 							hs.last_check, hs.status, hs.created_at, hs.updated_at,
-							s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, hs.last_message
+							s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, hs.last_message, hs.config, hs.heartbeat_token, hs.last_heartbeat, hs.heartbeat_ok, hs.heartbeat_message, hs.check_state, hs.attempts, hs.attempt_status, hs.status_history, hs.flap_score, hs.flapping, hs.activated_at
 						FROM host_services hs 
 						LEFT JOIN services s on (s.id = hs.service_id) 
 						WHERE host_id = $1`
@@ -266,6 +290,18 @@ func (m *postgresDBRepo) AllHosts() ([]models.Host, error) {
 				&hs.Service.CreatedAt,
 				&hs.Service.UpdatedAt,
 				&hs.LastMessage,
+				&hs.Config,
+				&hs.HeartbeatToken,
+				&hs.LastHeartbeat,
+				&hs.HeartbeatOK,
+				&hs.HeartbeatMessage,
+				&hs.CheckState,
+				&hs.Attempts,
+				&hs.AttemptStatus,
+				&hs.StatusHistory,
+				&hs.FlapScore,
+				&hs.Flapping,
+				&hs.ActivatedAt,
 			)
 			if err != nil {
 				log.Println(err)
@@ -291,7 +327,10 @@ func (m *postgresDBRepo) UpdateHostServiceStatus(hostID, serviceID, active int)
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	stmt := `UPDATE host_services SET active = $1 WHERE host_id = $2 AND service_id = $3`
+	// a heartbeat that has never been pinged is overdue counting from when it was turned on
+	stmt := `UPDATE host_services SET active = $1,
+				activated_at = CASE WHEN $1 = 1 AND active <> 1 THEN now() ELSE activated_at END
+			WHERE host_id = $2 AND service_id = $3`
 
 	_, err := m.DB.ExecContext(ctx, stmt, active, hostID, serviceID)
 	if err != nil {
@@ -327,17 +366,127 @@ func (m *postgresDBRepo) UpdateHostService(hs models.HostService) error {
 	return nil
 }
 
-// GetServicesByStatus returns all active services with a given status
+// UpdateHostServiceConfig saves the check configuration (json) for a host service
+func (m *postgresDBRepo) UpdateHostServiceConfig(id int, config string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if config == "" {
+		config = "{}"
+	}
+
+	stmt := `UPDATE host_services SET config = $1, updated_at = $2 WHERE id = $3`
+
+	_, err := m.DB.ExecContext(ctx, stmt, config, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetHostServiceByHeartbeatToken gets the host service whose heartbeat ping url uses token
+func (m *postgresDBRepo) GetHostServiceByHeartbeatToken(token string) (models.HostService, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var hs models.HostService
+
+	query := `SELECT id FROM host_services WHERE heartbeat_token = $1 AND heartbeat_token <> ''`
+
+	var id int
+	err := m.DB.QueryRowContext(ctx, query, token).Scan(&id)
+	if err != nil {
+		return hs, err
+	}
+
+	return m.GetHostServiceByID(id)
+}
+
+// UpdateHeartbeatToken sets the secret token used in a host service's heartbeat ping url
+func (m *postgresDBRepo) UpdateHeartbeatToken(id int, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET heartbeat_token = $1, updated_at = $2 WHERE id = $3`
+
+	_, err := m.DB.ExecContext(ctx, stmt, token, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RecordHeartbeat saves a ping received from a heartbeat monitored job
+func (m *postgresDBRepo) RecordHeartbeat(id, ok int, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET last_heartbeat = $1, heartbeat_ok = $2, heartbeat_message = $3 WHERE id = $4`
+
+	_, err := m.DB.ExecContext(ctx, stmt, time.Now(), ok, message, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateHostServiceCheckState saves what a check remembers about a host service between runs
+func (m *postgresDBRepo) UpdateHostServiceCheckState(id int, state string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET check_state = $1 WHERE id = $2`
+
+	_, err := m.DB.ExecContext(ctx, stmt, state, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateHostServiceAttempts records how many consecutive checks of a host service have reported a
+// status other than its current one, and the latest such status; zero attempts clears them
+func (m *postgresDBRepo) UpdateHostServiceAttempts(id, attempts int, status string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET attempts = $1, attempt_status = $2, last_check = $3 WHERE id = $4`
+
+	_, err := m.DB.ExecContext(ctx, stmt, attempts, status, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateHostServiceFlapping records the recent statuses of a host service, the flap score
+// computed from them and whether it is flapping
+func (m *postgresDBRepo) UpdateHostServiceFlapping(id int, history string, score float64, flapping int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE host_services SET status_history = $1, flap_score = $2, flapping = $3 WHERE id = $4`
+
+	_, err := m.DB.ExecContext(ctx, stmt, history, score, flapping, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetServicesByStatus returns all active services with a given status. Flapping services are
+// only returned for the status "flapping".
 func (m *postgresDBRepo) GetServicesByStatus(status string) ([]models.HostService, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	query := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.last_check, hs.status, hs.created_at, hs.updated_at,
-				h.host_name, s.service_name, hs.last_message
+				h.host_name, s.service_name, hs.last_message, hs.config, hs.heartbeat_token, hs.last_heartbeat, hs.heartbeat_ok, hs.heartbeat_message, hs.check_state, hs.attempts, hs.attempt_status, hs.status_history, hs.flap_score, hs.flapping, hs.activated_at
 				FROM host_services hs
 				LEFT JOIN hosts h ON (hs.host_id = h.id)
 				LEFT JOIN services s ON (hs.service_id = s.id)
-				WHERE status = $1 AND hs.active = 1
+				WHERE hs.active = 1 AND CASE WHEN $1 = 'flapping' THEN hs.flapping = 1
+					ELSE hs.status = $1 AND hs.flapping = 0 END
 				ORDER BY host_name, service_name`
 
 	var services []models.HostService
@@ -363,6 +512,18 @@ func (m *postgresDBRepo) GetServicesByStatus(status string) ([]models.HostServic
 			&h.HostName,
 			&h.Service.ServiceName,
 			&h.LastMessage,
+			&h.Config,
+			&h.HeartbeatToken,
+			&h.LastHeartbeat,
+			&h.HeartbeatOK,
+			&h.HeartbeatMessage,
+			&h.CheckState,
+			&h.Attempts,
+			&h.AttemptStatus,
+			&h.StatusHistory,
+			&h.FlapScore,
+			&h.Flapping,
+			&h.ActivatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -381,7 +542,7 @@ func (m *postgresDBRepo) GetHostServiceByID(id int) (models.HostService, error)
 
 	query := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.last_check,
 				hs.status, hs.created_at, hs.updated_at, s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at,
-				h.host_name, hs.last_message	
+				h.host_name, hs.last_message, hs.config, hs.heartbeat_token, hs.last_heartbeat, hs.heartbeat_ok, hs.heartbeat_message, hs.check_state, hs.attempts, hs.attempt_status, hs.status_history, hs.flap_score, hs.flapping, hs.activated_at	
 			FROM host_services hs
 			LEFT JOIN services s ON (hs.service_id = s.id)
 			LEFT JOIN hosts h ON (h.id = hs.host_id)
@@ -410,6 +571,18 @@ func (m *postgresDBRepo) GetHostServiceByID(id int) (models.HostService, error)
 		&hs.Service.UpdatedAt,
 		&hs.HostName,
 		&hs.LastMessage,
+		&hs.Config,
+		&hs.HeartbeatToken,
+		&hs.LastHeartbeat,
+		&hs.HeartbeatOK,
+		&hs.HeartbeatMessage,
+		&hs.CheckState,
+		&hs.Attempts,
+		&hs.AttemptStatus,
+		&hs.StatusHistory,
+		&hs.FlapScore,
+		&hs.Flapping,
+		&hs.ActivatedAt,
 	)
 	if err != nil {
 		log.Println(err)
@@ -424,11 +597,11 @@ func (m *postgresDBRepo) GetServicesToMonitor() ([]models.HostService, error) {
 	defer cancel()
 
 	query := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.last_check,
-				hs.status, hs.created_at, hs.updated_at, s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, h.host_name, hs.last_message
+				hs.status, hs.created_at, hs.updated_at, s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, h.host_name, hs.last_message, hs.config, hs.heartbeat_token, hs.last_heartbeat, hs.heartbeat_ok, hs.heartbeat_message, hs.check_state, hs.attempts, hs.attempt_status, hs.status_history, hs.flap_score, hs.flapping, hs.activated_at
 			FROM host_services hs
 			LEFT JOIN services s ON (hs.service_id = s.id)
 			LEFT JOIN hosts h ON (h.id = hs.host_id)
-			WHERE h.active = 1 AND hs.active = 1`
+			WHERE h.active = 1 AND hs.active = 1 AND (h.agent_id = 0 OR s.service_name = 'Heartbeat')`
 
 	var services []models.HostService
 
@@ -459,6 +632,18 @@ func (m *postgresDBRepo) GetServicesToMonitor() ([]models.HostService, error) {
 			&h.Service.UpdatedAt,
 			&h.HostName,
 			&h.LastMessage,
+			&h.Config,
+			&h.HeartbeatToken,
+			&h.LastHeartbeat,
+			&h.HeartbeatOK,
+			&h.HeartbeatMessage,
+			&h.CheckState,
+			&h.Attempts,
+			&h.AttemptStatus,
+			&h.StatusHistory,
+			&h.FlapScore,
+			&h.Flapping,
+			&h.ActivatedAt,
 		)
 		if err != nil {
 			log.Println(err)
@@ -475,7 +660,7 @@ func (m *postgresDBRepo) GetHostServiceByHostIdServiceId(hostID, serviceID int)
 	defer cancel()
 
 	query := `SELECT hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, hs.last_check, hs.status,
-				hs.created_at, hs.updated_at, s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, h.host_name, hs.last_message
+				hs.created_at, hs.updated_at, s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, h.host_name, hs.last_message, hs.config, hs.heartbeat_token, hs.last_heartbeat, hs.heartbeat_ok, hs.heartbeat_message, hs.check_state, hs.attempts, hs.attempt_status, hs.status_history, hs.flap_score, hs.flapping, hs.activated_at
 			FROM host_services hs
 			LEFT JOIN services s ON (hs.service_id = s.id)
 			LEFT JOIN hosts h ON (hs.host_id = h.id)
@@ -504,6 +689,18 @@ func (m *postgresDBRepo) GetHostServiceByHostIdServiceId(hostID, serviceID int)
 		&hs.Service.UpdatedAt,
 		&hs.HostName,
 		&hs.LastMessage,
+		&hs.Config,
+		&hs.HeartbeatToken,
+		&hs.LastHeartbeat,
+		&hs.HeartbeatOK,
+		&hs.HeartbeatMessage,
+		&hs.CheckState,
+		&hs.Attempts,
+		&hs.AttemptStatus,
+		&hs.StatusHistory,
+		&hs.FlapScore,
+		&hs.Flapping,
+		&hs.ActivatedAt,
 	)
 	if err != nil {
 		return hs, err
@@ -519,8 +716,8 @@ func (m *postgresDBRepo) InsertEvent(e models.Event) error {
 	defer cancel()
 
 	stmt := `INSERT INTO events (host_service_id, event_type, host_id, service_name, host_name, message,
-				created_at, updated_at)
-			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`
+				maintenance, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`
 
 	_, err := m.DB.ExecContext(ctx, stmt,
 		e.HostServiceID,
@@ -529,6 +726,7 @@ func (m *postgresDBRepo) InsertEvent(e models.Event) error {
 		e.ServiceName,
 		e.HostName,
 		e.Message,
+		e.Maintenance,
 		time.Now(),
 		time.Now(),
 	)
@@ -538,13 +736,38 @@ func (m *postgresDBRepo) InsertEvent(e models.Event) error {
 	return nil
 }
 
+// InsertMetrics records the metrics measured by one check of a host service
+func (m *postgresDBRepo) InsertMetrics(hostServiceID int, metrics map[string]float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO host_service_metrics (host_service_id, name, value, created_at)
+			VALUES ($1,$2,$3,$4)`
+
+	now := time.Now()
+	for name, value := range metrics {
+		_, err = tx.ExecContext(ctx, stmt, hostServiceID, name, value, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 // GetAllEvents gets all events
 func (m *postgresDBRepo) GetAllEvents() ([]models.Event, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	query := `SELECT id, event_type, host_service_id, host_id, service_name, host_name,
-				message, created_at, updated_at FROM events ORDER BY created_at`
+				message, maintenance, created_at, updated_at FROM events ORDER BY created_at`
 
 	var events []models.Event
 
@@ -565,6 +788,7 @@ func (m *postgresDBRepo) GetAllEvents() ([]models.Event, error) {
 			&ev.ServiceName,
 			&ev.HostName,
 			&ev.Message,
+			&ev.Maintenance,
 			&ev.CreatedAt,
 			&ev.UpdatedAt,
 		)
@@ -0,0 +1,128 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// maintenanceWindowQuery selects maintenance windows with the names of their host and service. A
+// window for the whole host has a NULL host_service_id, which is read as 0.
+const maintenanceWindowQuery = `SELECT mw.id, mw.description, mw.host_id, coalesce(mw.host_service_id, 0), mw.recurrence,
+			mw.starts_at, mw.ends_at, mw.weekday, mw.start_time, mw.end_time, mw.timezone,
+			h.host_name, coalesce(s.service_name, ''), mw.created_at, mw.updated_at
+		FROM maintenance_windows mw
+		LEFT JOIN hosts h ON (h.id = mw.host_id)
+		LEFT JOIN host_services hs ON (hs.id = mw.host_service_id)
+		LEFT JOIN services s ON (s.id = hs.service_id)`
+
+// AllMaintenanceWindows returns all maintenance windows
+func (m *postgresDBRepo) AllMaintenanceWindows() ([]models.MaintenanceWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, maintenanceWindowQuery+` ORDER BY h.host_name, mw.id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanMaintenanceWindows(rows)
+}
+
+// GetMaintenanceWindowsForHost returns the maintenance windows of a host and of its services
+func (m *postgresDBRepo) GetMaintenanceWindowsForHost(hostID int) ([]models.MaintenanceWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, maintenanceWindowQuery+` WHERE mw.host_id = $1 ORDER BY mw.id`, hostID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMaintenanceWindows(rows)
+}
+
+// scanMaintenanceWindows reads the rows of maintenanceWindowQuery, and closes them
+func scanMaintenanceWindows(rows *sql.Rows) ([]models.MaintenanceWindow, error) {
+	defer rows.Close()
+
+	var windows []models.MaintenanceWindow
+	for rows.Next() {
+		var mw models.MaintenanceWindow
+		err := rows.Scan(
+			&mw.ID,
+			&mw.Description,
+			&mw.HostID,
+			&mw.HostServiceID,
+			&mw.Recurrence,
+			&mw.StartsAt,
+			&mw.EndsAt,
+			&mw.Weekday,
+			&mw.StartTime,
+			&mw.EndTime,
+			&mw.Timezone,
+			&mw.HostName,
+			&mw.ServiceName,
+			&mw.CreatedAt,
+			&mw.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		windows = append(windows, mw)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+// InsertMaintenanceWindow inserts a maintenance window into the database
+func (m *postgresDBRepo) InsertMaintenanceWindow(mw models.MaintenanceWindow) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `INSERT INTO maintenance_windows (description, host_id, host_service_id, recurrence, starts_at, ends_at,
+				weekday, start_time, end_time, timezone, created_at, updated_at)
+				VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6, $7, $8, $9, $10, $11, $12) returning id`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, query,
+		mw.Description,
+		mw.HostID,
+		mw.HostServiceID,
+		mw.Recurrence,
+		mw.StartsAt,
+		mw.EndsAt,
+		mw.Weekday,
+		mw.StartTime,
+		mw.EndTime,
+		mw.Timezone,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+func (m *postgresDBRepo) DeleteMaintenanceWindow(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
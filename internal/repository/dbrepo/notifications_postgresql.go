@@ -0,0 +1,253 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/wtran29/spectre/internal/models"
+)
+
+// AllNotificationChannels returns all notification channels
+func (m *postgresDBRepo) AllNotificationChannels() ([]models.NotificationChannel, error) {
+	return m.queryNotificationChannels(`SELECT id, name, channel_type, config, enabled, created_at, updated_at
+			FROM notification_channels ORDER BY name, id`)
+}
+
+// GetEnabledNotificationChannels returns the notification channels that are turned on
+func (m *postgresDBRepo) GetEnabledNotificationChannels() ([]models.NotificationChannel, error) {
+	return m.queryNotificationChannels(`SELECT id, name, channel_type, config, enabled, created_at, updated_at
+			FROM notification_channels WHERE enabled = 1 ORDER BY name, id`)
+}
+
+// queryNotificationChannels runs a query returning notification channels
+func (m *postgresDBRepo) queryNotificationChannels(query string, args ...interface{}) ([]models.NotificationChannel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []models.NotificationChannel
+	for rows.Next() {
+		var ch models.NotificationChannel
+		err = rows.Scan(
+			&ch.ID,
+			&ch.Name,
+			&ch.ChannelType,
+			&ch.Config,
+			&ch.Enabled,
+			&ch.CreatedAt,
+			&ch.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+// GetNotificationChannelByID returns a notification channel by id
+func (m *postgresDBRepo) GetNotificationChannelByID(id int) (models.NotificationChannel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT id, name, channel_type, config, enabled, created_at, updated_at
+			FROM notification_channels WHERE id = $1`
+
+	var ch models.NotificationChannel
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&ch.ID,
+		&ch.Name,
+		&ch.ChannelType,
+		&ch.Config,
+		&ch.Enabled,
+		&ch.CreatedAt,
+		&ch.UpdatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		return ch, err
+	}
+
+	return ch, nil
+}
+
+// InsertNotificationChannel inserts a notification channel into the database
+func (m *postgresDBRepo) InsertNotificationChannel(ch models.NotificationChannel) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `INSERT INTO notification_channels (name, channel_type, config, enabled, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6) returning id`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, query,
+		ch.Name,
+		ch.ChannelType,
+		ch.Config,
+		ch.Enabled,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// UpdateNotificationChannel updates the name, settings and enabled flag of a notification channel
+func (m *postgresDBRepo) UpdateNotificationChannel(ch models.NotificationChannel) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE notification_channels SET name = $1, config = $2, enabled = $3, updated_at = $4
+				WHERE id = $5`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		ch.Name,
+		ch.Config,
+		ch.Enabled,
+		time.Now(),
+		ch.ID,
+	)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteNotificationChannel deletes a notification channel
+func (m *postgresDBRepo) DeleteNotificationChannel(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM notification_channels WHERE id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// InsertWebhookDelivery records a delivery to a webhook channel
+func (m *postgresDBRepo) InsertWebhookDelivery(d models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `INSERT INTO webhook_deliveries (notification_channel_id, event_type, host_service_id, payload,
+				attempts, status_code, error, delivered, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, query,
+		d.NotificationChannelID,
+		d.EventType,
+		d.HostServiceID,
+		d.Payload,
+		d.Attempts,
+		d.StatusCode,
+		d.Error,
+		d.Delivered,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// GetWebhookDeliveries returns the most recent deliveries to a webhook channel, newest first
+func (m *postgresDBRepo) GetWebhookDeliveries(channelID, limit int) ([]models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT id, notification_channel_id, event_type, host_service_id, payload, attempts, status_code,
+				error, delivered, created_at, updated_at
+			FROM webhook_deliveries WHERE notification_channel_id = $1
+			ORDER BY id DESC LIMIT $2`
+
+	rows, err := m.DB.QueryContext(ctx, query, channelID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err = rows.Scan(
+			&d.ID,
+			&d.NotificationChannelID,
+			&d.EventType,
+			&d.HostServiceID,
+			&d.Payload,
+			&d.Attempts,
+			&d.StatusCode,
+			&d.Error,
+			&d.Delivered,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// GetWebhookDeliveryByID returns a delivery to a webhook channel by id
+func (m *postgresDBRepo) GetWebhookDeliveryByID(id int) (models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `SELECT id, notification_channel_id, event_type, host_service_id, payload, attempts, status_code,
+				error, delivered, created_at, updated_at
+			FROM webhook_deliveries WHERE id = $1`
+
+	var d models.WebhookDelivery
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&d.ID,
+		&d.NotificationChannelID,
+		&d.EventType,
+		&d.HostServiceID,
+		&d.Payload,
+		&d.Attempts,
+		&d.StatusCode,
+		&d.Error,
+		&d.Delivered,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		return d, err
+	}
+
+	return d, nil
+}
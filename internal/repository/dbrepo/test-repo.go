@@ -1,6 +1,9 @@
 package dbrepo
 
 import (
+	"database/sql"
+	"time"
+
 	"github.com/wtran29/spectre/internal/models"
 )
 
@@ -81,8 +84,8 @@ func (m *testDBRepo) AllHosts() ([]models.Host, error) {
 func (m *testDBRepo) UpdateHostServiceStatus(hostID, serviceID, active int) error {
 	return nil
 }
-func (m *testDBRepo) GetAllServiceStatusCounts() (int, int, int, int, error) {
-	return 1, 0, 0, 0, nil
+func (m *testDBRepo) GetAllServiceStatusCounts() (models.ServiceStatusCounts, error) {
+	return models.ServiceStatusCounts{Pending: 1}, nil
 }
 func (m *testDBRepo) GetServicesByStatus(status string) ([]models.HostService, error) {
 	var hs []models.HostService
@@ -95,6 +98,32 @@ func (m *testDBRepo) GetHostServiceByID(id int) (models.HostService, error) {
 func (m *testDBRepo) UpdateHostService(hs models.HostService) error {
 	return nil
 }
+func (m *testDBRepo) UpdateHostServiceConfig(id int, config string) error {
+	return nil
+}
+func (m *testDBRepo) GetHostServiceByHeartbeatToken(token string) (models.HostService, error) {
+	var hs models.HostService
+	if token == "valid-token" {
+		hs.ID = 1
+		hs.HeartbeatToken = token
+	}
+	return hs, nil
+}
+func (m *testDBRepo) UpdateHeartbeatToken(id int, token string) error {
+	return nil
+}
+func (m *testDBRepo) RecordHeartbeat(id, ok int, message string) error {
+	return nil
+}
+func (m *testDBRepo) UpdateHostServiceAttempts(id, attempts int, status string) error {
+	return nil
+}
+func (m *testDBRepo) UpdateHostServiceFlapping(id int, history string, score float64, flapping int) error {
+	return nil
+}
+func (m *testDBRepo) UpdateHostServiceCheckState(id int, state string) error {
+	return nil
+}
 func (m *testDBRepo) GetServicesToMonitor() ([]models.HostService, error) {
 	var hs []models.HostService
 	return hs, nil
@@ -107,6 +136,117 @@ func (m *testDBRepo) GetAllEvents() ([]models.Event, error) {
 	var events []models.Event
 	return events, nil
 }
+func (m *testDBRepo) InsertMetrics(hostServiceID int, metrics map[string]float64) error {
+	return nil
+}
 func (m *testDBRepo) InsertEvent(e models.Event) error {
 	return nil
 }
+
+func (m *testDBRepo) AllAgents() ([]models.Agent, error) {
+	var agents []models.Agent
+	return agents, nil
+}
+func (m *testDBRepo) InsertAgent(a models.Agent) (int, error) {
+	return 1, nil
+}
+func (m *testDBRepo) GetAgentByTokenHash(hash string) (models.Agent, error) {
+	var a models.Agent
+	// sha256 of "agent-token"
+	if hash == "eb47b9ce4840a5b3ea138b8691253b78035b9289d9014409067e9844c272ef99" {
+		return models.Agent{ID: 1, Name: "office", Active: 1}, nil
+	}
+	return a, sql.ErrNoRows
+}
+func (m *testDBRepo) UpdateAgentLastSeen(id int) error {
+	return nil
+}
+func (m *testDBRepo) DeleteAgent(id int) error {
+	return nil
+}
+func (m *testDBRepo) GetServicesForAgent(agentID int) ([]models.HostService, error) {
+	var hs []models.HostService
+	return hs, nil
+}
+
+func (m *testDBRepo) GetCertificates(filter models.CertificateFilter) ([]models.Certificate, error) {
+	certs := []models.Certificate{{
+		ID:           1,
+		Source:       "www.example.com:443",
+		Subject:      "www.example.com",
+		Issuer:       "Example CA",
+		SerialNumber: "0A-1B",
+		Thumbprint:   "AB:CD",
+		DNSNames:     "www.example.com, example.com",
+		NotAfter:     time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+		HostID:       1,
+		HostName:     "web, primary",
+		ServiceName:  "SSL Certificate",
+	}}
+	return certs, nil
+}
+func (m *testDBRepo) GetCertificateIssuers() ([]string, error) {
+	return []string{"Example CA"}, nil
+}
+func (m *testDBRepo) ReplaceCertificates(hostServiceID int, certs []models.Certificate) error {
+	return nil
+}
+
+func (m *testDBRepo) AllMaintenanceWindows() ([]models.MaintenanceWindow, error) {
+	var windows []models.MaintenanceWindow
+	return windows, nil
+}
+func (m *testDBRepo) GetMaintenanceWindowsForHost(hostID int) ([]models.MaintenanceWindow, error) {
+	var windows []models.MaintenanceWindow
+	return windows, nil
+}
+func (m *testDBRepo) InsertMaintenanceWindow(mw models.MaintenanceWindow) (int, error) {
+	return 1, nil
+}
+func (m *testDBRepo) DeleteMaintenanceWindow(id int) error {
+	return nil
+}
+
+func (m *testDBRepo) AllDependencies() ([]models.Dependency, error) {
+	var deps []models.Dependency
+	return deps, nil
+}
+func (m *testDBRepo) InsertDependency(d models.Dependency) (int, error) {
+	return 1, nil
+}
+func (m *testDBRepo) DeleteDependency(id int) error {
+	return nil
+}
+
+func (m *testDBRepo) AllNotificationChannels() ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	return channels, nil
+}
+func (m *testDBRepo) GetEnabledNotificationChannels() ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	return channels, nil
+}
+func (m *testDBRepo) GetNotificationChannelByID(id int) (models.NotificationChannel, error) {
+	var ch models.NotificationChannel
+	return ch, nil
+}
+func (m *testDBRepo) InsertNotificationChannel(ch models.NotificationChannel) (int, error) {
+	return 1, nil
+}
+func (m *testDBRepo) UpdateNotificationChannel(ch models.NotificationChannel) error {
+	return nil
+}
+func (m *testDBRepo) DeleteNotificationChannel(id int) error {
+	return nil
+}
+func (m *testDBRepo) InsertWebhookDelivery(d models.WebhookDelivery) (int, error) {
+	return 1, nil
+}
+func (m *testDBRepo) GetWebhookDeliveries(channelID, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	return deliveries, nil
+}
+func (m *testDBRepo) GetWebhookDeliveryByID(id int) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	return d, nil
+}
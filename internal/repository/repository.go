@@ -28,12 +28,55 @@ type DatabaseRepo interface {
 	UpdateHost(h models.Host) error
 	AllHosts() ([]models.Host, error)
 	UpdateHostServiceStatus(hostID, serviceID, active int) error
-	GetAllServiceStatusCounts() (int, int, int, int, error)
+	GetAllServiceStatusCounts() (models.ServiceStatusCounts, error)
 	GetServicesByStatus(status string) ([]models.HostService, error)
 	GetHostServiceByID(id int) (models.HostService, error)
 	UpdateHostService(hs models.HostService) error
+	UpdateHostServiceConfig(id int, config string) error
+	GetHostServiceByHeartbeatToken(token string) (models.HostService, error)
+	UpdateHeartbeatToken(id int, token string) error
+	RecordHeartbeat(id, ok int, message string) error
+	UpdateHostServiceCheckState(id int, state string) error
+	UpdateHostServiceAttempts(id, attempts int, status string) error
+	UpdateHostServiceFlapping(id int, history string, score float64, flapping int) error
 	GetServicesToMonitor() ([]models.HostService, error)
 	GetHostServiceByHostIdServiceId(hostID, serviceID int) (models.HostService, error)
 	GetAllEvents() ([]models.Event, error)
 	InsertEvent(e models.Event) error
+	InsertMetrics(hostServiceID int, metrics map[string]float64) error
+
+	// remote agents
+	AllAgents() ([]models.Agent, error)
+	InsertAgent(a models.Agent) (int, error)
+	GetAgentByTokenHash(hash string) (models.Agent, error)
+	UpdateAgentLastSeen(id int) error
+	DeleteAgent(id int) error
+	GetServicesForAgent(agentID int) ([]models.HostService, error)
+
+	// certificate inventory
+	GetCertificates(filter models.CertificateFilter) ([]models.Certificate, error)
+	GetCertificateIssuers() ([]string, error)
+	ReplaceCertificates(hostServiceID int, certs []models.Certificate) error
+
+	// maintenance windows
+	AllMaintenanceWindows() ([]models.MaintenanceWindow, error)
+	GetMaintenanceWindowsForHost(hostID int) ([]models.MaintenanceWindow, error)
+	InsertMaintenanceWindow(mw models.MaintenanceWindow) (int, error)
+	DeleteMaintenanceWindow(id int) error
+
+	// host dependencies
+	AllDependencies() ([]models.Dependency, error)
+	InsertDependency(d models.Dependency) (int, error)
+	DeleteDependency(id int) error
+
+	// notification channels
+	AllNotificationChannels() ([]models.NotificationChannel, error)
+	GetEnabledNotificationChannels() ([]models.NotificationChannel, error)
+	GetNotificationChannelByID(id int) (models.NotificationChannel, error)
+	InsertNotificationChannel(ch models.NotificationChannel) (int, error)
+	UpdateNotificationChannel(ch models.NotificationChannel) error
+	DeleteNotificationChannel(id int) error
+	InsertWebhookDelivery(d models.WebhookDelivery) (int, error)
+	GetWebhookDeliveries(channelID, limit int) ([]models.WebhookDelivery, error)
+	GetWebhookDeliveryByID(id int) (models.WebhookDelivery, error)
 }
@@ -0,0 +1,7 @@
+UPDATE services SET service_name = o.service_name
+FROM services_original_names o
+WHERE services.id = o.id;
+
+DROP TABLE services_original_names;
+
+ALTER TABLE host_services DROP COLUMN IF EXISTS config;
@@ -0,0 +1,16 @@
+-- per-check configuration, decoded by the checker registered for the service
+ALTER TABLE host_services ADD COLUMN config jsonb NOT NULL DEFAULT '{}';
+
+-- keep the names of the built-in services that are renamed below, so the down migration can put
+-- them back
+CREATE TABLE services_original_names AS
+SELECT id, service_name FROM services
+WHERE lower(trim(service_name)) IN ('http', 'https', 'ssl certificate')
+  AND service_name NOT IN ('HTTP', 'HTTPS', 'SSL Certificate');
+
+-- checkers are looked up by service_name, so make sure the built-in services use the registered
+-- names; services are matched by name rather than id, so a database seeded in another order keeps
+-- its other services as they are
+UPDATE services SET service_name = 'HTTP' WHERE lower(trim(service_name)) = 'http';
+UPDATE services SET service_name = 'HTTPS' WHERE lower(trim(service_name)) = 'https';
+UPDATE services SET service_name = 'SSL Certificate' WHERE lower(trim(service_name)) = 'ssl certificate';
@@ -0,0 +1,4 @@
+DELETE FROM events WHERE host_service_id IN (
+    SELECT id FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'TCP'));
+DELETE FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'TCP');
+DELETE FROM services WHERE service_name = 'TCP';
@@ -0,0 +1,9 @@
+INSERT INTO services (service_name, active, icon, created_at, updated_at)
+VALUES ('TCP', 1, 'fas fa-network-wired', now(), now());
+
+-- give every existing host an inactive TCP host service, as InsertHost does for new hosts;
+-- its checker supplies the defaults for any setting left out of the config
+INSERT INTO host_services (host_id, service_id, active, schedule_number, schedule_unit, status, config, created_at, updated_at)
+SELECT h.id, s.id, 0, 3, 'm', 'pending', '{}', now(), now()
+FROM hosts h, services s
+WHERE s.service_name = 'TCP';
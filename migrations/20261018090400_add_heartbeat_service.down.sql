@@ -0,0 +1,10 @@
+DELETE FROM events WHERE host_service_id IN (
+    SELECT id FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'Heartbeat'));
+DELETE FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'Heartbeat');
+DELETE FROM services WHERE service_name = 'Heartbeat';
+
+DROP INDEX IF EXISTS host_services_heartbeat_token_idx;
+ALTER TABLE host_services DROP COLUMN IF EXISTS heartbeat_message;
+ALTER TABLE host_services DROP COLUMN IF EXISTS heartbeat_ok;
+ALTER TABLE host_services DROP COLUMN IF EXISTS last_heartbeat;
+ALTER TABLE host_services DROP COLUMN IF EXISTS heartbeat_token;
@@ -0,0 +1,15 @@
+ALTER TABLE host_services ADD COLUMN heartbeat_token varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE host_services ADD COLUMN last_heartbeat timestamp NOT NULL DEFAULT '0001-01-01 00:00:00';
+ALTER TABLE host_services ADD COLUMN heartbeat_ok integer NOT NULL DEFAULT 0;
+ALTER TABLE host_services ADD COLUMN heartbeat_message varchar(255) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX host_services_heartbeat_token_idx ON host_services (heartbeat_token) WHERE heartbeat_token <> '';
+
+INSERT INTO services (service_name, active, icon, created_at, updated_at)
+VALUES ('Heartbeat', 1, 'fas fa-heartbeat', now(), now());
+
+-- give every existing host an inactive Heartbeat host service, as InsertHost does for new hosts;
+-- its checker supplies the defaults for any setting left out of the config
+INSERT INTO host_services (host_id, service_id, active, schedule_number, schedule_unit, status, config, created_at, updated_at)
+SELECT h.id, s.id, 0, 3, 'm', 'pending', '{}', now(), now()
+FROM hosts h, services s
+WHERE s.service_name = 'Heartbeat';
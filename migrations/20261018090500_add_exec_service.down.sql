@@ -0,0 +1,6 @@
+DELETE FROM events WHERE host_service_id IN (
+    SELECT id FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'Exec'));
+DELETE FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'Exec');
+DELETE FROM services WHERE service_name = 'Exec';
+
+DROP TABLE IF EXISTS host_service_metrics;
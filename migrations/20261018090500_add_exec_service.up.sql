@@ -0,0 +1,18 @@
+CREATE TABLE host_service_metrics (
+    id serial PRIMARY KEY,
+    host_service_id integer NOT NULL REFERENCES host_services (id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    value double precision NOT NULL,
+    created_at timestamp NOT NULL
+);
+CREATE INDEX host_service_metrics_host_service_id_created_at_idx ON host_service_metrics (host_service_id, created_at);
+
+INSERT INTO services (service_name, active, icon, created_at, updated_at)
+VALUES ('Exec', 1, 'fas fa-terminal', now(), now());
+
+-- give every existing host an inactive Exec host service, as InsertHost does for new hosts;
+-- its checker supplies the defaults for any setting left out of the config
+INSERT INTO host_services (host_id, service_id, active, schedule_number, schedule_unit, status, config, created_at, updated_at)
+SELECT h.id, s.id, 0, 3, 'm', 'pending', '{}', now(), now()
+FROM hosts h, services s
+WHERE s.service_name = 'Exec';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS hosts_agent_id_idx;
+ALTER TABLE hosts DROP COLUMN IF EXISTS agent_id;
+DROP TABLE IF EXISTS agents;
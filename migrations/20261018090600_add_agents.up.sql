@@ -0,0 +1,13 @@
+CREATE TABLE agents (
+    id serial PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    last_seen timestamp NOT NULL DEFAULT '0001-01-01 00:00:00',
+    active integer NOT NULL DEFAULT 1,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+-- 0 means the server itself runs the host's checks
+ALTER TABLE hosts ADD COLUMN agent_id integer NOT NULL DEFAULT 0;
+CREATE INDEX hosts_agent_id_idx ON hosts (agent_id);
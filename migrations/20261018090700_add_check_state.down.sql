@@ -0,0 +1,3 @@
+DELETE FROM preferences WHERE name IN ('ssl_warning_days', 'ssl_problem_days');
+
+ALTER TABLE host_services DROP COLUMN check_state;
@@ -0,0 +1,5 @@
+ALTER TABLE host_services ADD COLUMN check_state text NOT NULL DEFAULT '';
+
+INSERT INTO preferences (name, preference, created_at, updated_at)
+VALUES ('ssl_warning_days', '30', now(), now()),
+       ('ssl_problem_days', '7', now(), now());
@@ -0,0 +1,6 @@
+DELETE FROM events WHERE host_service_id IN (
+    SELECT id FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'Certificate Files'));
+DELETE FROM host_services WHERE service_id = (SELECT id FROM services WHERE service_name = 'Certificate Files');
+DELETE FROM services WHERE service_name = 'Certificate Files';
+
+DROP TABLE IF EXISTS certificates;
@@ -0,0 +1,26 @@
+CREATE TABLE certificates (
+    id serial PRIMARY KEY,
+    host_service_id integer NOT NULL REFERENCES host_services (id) ON DELETE CASCADE,
+    source varchar(1024) NOT NULL,
+    subject varchar(1024) NOT NULL,
+    issuer varchar(1024) NOT NULL,
+    serial_number varchar(255) NOT NULL,
+    thumbprint varchar(255) NOT NULL,
+    dns_names text NOT NULL DEFAULT '',
+    not_after timestamp NOT NULL,
+    key_mismatch boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+CREATE INDEX certificates_host_service_id_idx ON certificates (host_service_id);
+CREATE INDEX certificates_not_after_idx ON certificates (not_after);
+
+INSERT INTO services (service_name, active, icon, created_at, updated_at)
+VALUES ('Certificate Files', 1, 'fas fa-file-contract', now(), now());
+
+-- give every existing host an inactive Certificate Files host service, as InsertHost does for new hosts;
+-- its checker supplies the defaults for any setting left out of the config
+INSERT INTO host_services (host_id, service_id, active, schedule_number, schedule_unit, status, config, created_at, updated_at)
+SELECT h.id, s.id, 0, 3, 'm', 'pending', '{}', now(), now()
+FROM hosts h, services s
+WHERE s.service_name = 'Certificate Files';
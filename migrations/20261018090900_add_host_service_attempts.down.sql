@@ -0,0 +1,2 @@
+ALTER TABLE host_services DROP COLUMN attempt_status;
+ALTER TABLE host_services DROP COLUMN attempts;
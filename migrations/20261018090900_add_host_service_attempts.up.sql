@@ -0,0 +1,2 @@
+ALTER TABLE host_services ADD COLUMN attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE host_services ADD COLUMN attempt_status varchar(255) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DELETE FROM preferences WHERE name IN ('flap_start_percent', 'flap_stop_percent');
+
+ALTER TABLE host_services DROP COLUMN flapping;
+ALTER TABLE host_services DROP COLUMN flap_score;
+ALTER TABLE host_services DROP COLUMN status_history;
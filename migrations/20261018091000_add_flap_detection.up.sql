@@ -0,0 +1,7 @@
+ALTER TABLE host_services ADD COLUMN status_history varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE host_services ADD COLUMN flap_score double precision NOT NULL DEFAULT 0;
+ALTER TABLE host_services ADD COLUMN flapping integer NOT NULL DEFAULT 0;
+
+INSERT INTO preferences (name, preference, created_at, updated_at)
+VALUES ('flap_start_percent', '50', now(), now()),
+       ('flap_stop_percent', '25', now(), now());
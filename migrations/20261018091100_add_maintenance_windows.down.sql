@@ -0,0 +1,3 @@
+ALTER TABLE events DROP COLUMN maintenance;
+
+DROP TABLE maintenance_windows;
@@ -0,0 +1,20 @@
+CREATE TABLE maintenance_windows (
+    id serial PRIMARY KEY,
+    description varchar(255) NOT NULL DEFAULT '',
+    host_id integer NOT NULL REFERENCES hosts (id) ON DELETE CASCADE,
+    -- 0 means every service on the host
+    host_service_id integer NOT NULL DEFAULT 0,
+    -- '' for a one-off window, otherwise 'daily' or 'weekly'
+    recurrence varchar(16) NOT NULL DEFAULT '',
+    starts_at timestamp NOT NULL DEFAULT '0001-01-01 00:00:00',
+    ends_at timestamp NOT NULL DEFAULT '0001-01-01 00:00:00',
+    weekday integer NOT NULL DEFAULT 0,
+    start_time varchar(5) NOT NULL DEFAULT '',
+    end_time varchar(5) NOT NULL DEFAULT '',
+    timezone varchar(64) NOT NULL DEFAULT 'UTC',
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+CREATE INDEX maintenance_windows_host_id_idx ON maintenance_windows (host_id);
+
+ALTER TABLE events ADD COLUMN maintenance integer NOT NULL DEFAULT 0;
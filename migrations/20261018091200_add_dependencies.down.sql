@@ -0,0 +1,3 @@
+UPDATE host_services SET status = 'problem' WHERE status = 'unreachable';
+
+DROP TABLE dependencies;
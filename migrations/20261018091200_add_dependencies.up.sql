@@ -0,0 +1,13 @@
+CREATE TABLE dependencies (
+    id serial PRIMARY KEY,
+    host_id integer NOT NULL REFERENCES hosts (id) ON DELETE CASCADE,
+    -- 0 means the whole host depends on the parent
+    host_service_id integer NOT NULL DEFAULT 0,
+    parent_host_id integer NOT NULL REFERENCES hosts (id) ON DELETE CASCADE,
+    -- 0 means the parent is the whole host
+    parent_host_service_id integer NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    UNIQUE (host_id, host_service_id, parent_host_id, parent_host_service_id)
+);
+CREATE INDEX dependencies_parent_host_id_idx ON dependencies (parent_host_id);
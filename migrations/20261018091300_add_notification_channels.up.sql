@@ -0,0 +1,23 @@
+CREATE TABLE notification_channels (
+    id serial PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    channel_type varchar(255) NOT NULL,
+    config text NOT NULL DEFAULT '',
+    enabled integer NOT NULL DEFAULT 1,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+-- carry the email and text message recipients over from the site preferences
+INSERT INTO notification_channels (name, channel_type, config, enabled, created_at, updated_at)
+SELECT 'Email', 'email',
+       json_build_object('to_name', coalesce((SELECT preference FROM preferences WHERE name = 'notify_name'), ''),
+                         'to_address', coalesce((SELECT preference FROM preferences WHERE name = 'notify_email'), ''))::text,
+       CASE WHEN (SELECT preference FROM preferences WHERE name = 'notify_via_email') = '1' THEN 1 ELSE 0 END,
+       now(), now();
+
+INSERT INTO notification_channels (name, channel_type, config, enabled, created_at, updated_at)
+SELECT 'Text Message', 'sms',
+       json_build_object('to', coalesce((SELECT preference FROM preferences WHERE name = 'sms_notify_number'), ''))::text,
+       CASE WHEN (SELECT preference FROM preferences WHERE name = 'notify_via_sms') = '1' THEN 1 ELSE 0 END,
+       now(), now();
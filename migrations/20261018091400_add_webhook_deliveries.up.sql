@@ -0,0 +1,14 @@
+CREATE TABLE webhook_deliveries (
+    id serial PRIMARY KEY,
+    notification_channel_id integer NOT NULL REFERENCES notification_channels (id) ON DELETE CASCADE,
+    event_type varchar(255) NOT NULL DEFAULT '',
+    host_service_id integer NOT NULL DEFAULT 0,
+    payload text NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 0,
+    status_code integer NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    delivered integer NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+CREATE INDEX webhook_deliveries_notification_channel_id_idx ON webhook_deliveries (notification_channel_id);
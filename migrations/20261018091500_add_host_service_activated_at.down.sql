@@ -0,0 +1 @@
+ALTER TABLE host_services DROP COLUMN activated_at;
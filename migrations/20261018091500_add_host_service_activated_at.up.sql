@@ -0,0 +1,4 @@
+-- when monitoring of a host service was last turned on; a heartbeat that has never been pinged is
+-- overdue counting from then
+ALTER TABLE host_services ADD COLUMN activated_at timestamp NOT NULL DEFAULT now();
+UPDATE host_services SET activated_at = created_at;
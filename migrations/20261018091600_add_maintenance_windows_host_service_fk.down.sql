@@ -0,0 +1,4 @@
+ALTER TABLE maintenance_windows DROP CONSTRAINT IF EXISTS maintenance_windows_host_service_id_fkey;
+UPDATE maintenance_windows SET host_service_id = 0 WHERE host_service_id IS NULL;
+ALTER TABLE maintenance_windows ALTER COLUMN host_service_id SET DEFAULT 0;
+ALTER TABLE maintenance_windows ALTER COLUMN host_service_id SET NOT NULL;
@@ -0,0 +1,10 @@
+-- a window for a single service goes when the service is removed from its host; NULL now means
+-- every service on the host
+ALTER TABLE maintenance_windows ALTER COLUMN host_service_id DROP NOT NULL;
+ALTER TABLE maintenance_windows ALTER COLUMN host_service_id DROP DEFAULT;
+UPDATE maintenance_windows SET host_service_id = NULL WHERE host_service_id = 0;
+DELETE FROM maintenance_windows mw
+    WHERE mw.host_service_id IS NOT NULL
+      AND NOT EXISTS (SELECT 1 FROM host_services hs WHERE hs.id = mw.host_service_id);
+ALTER TABLE maintenance_windows ADD CONSTRAINT maintenance_windows_host_service_id_fkey
+    FOREIGN KEY (host_service_id) REFERENCES host_services (id) ON DELETE CASCADE;
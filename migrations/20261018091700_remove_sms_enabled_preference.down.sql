@@ -0,0 +1,5 @@
+INSERT INTO preferences (name, preference, created_at, updated_at)
+VALUES ('sms_enabled',
+        CASE WHEN EXISTS (SELECT 1 FROM notification_channels WHERE channel_type = 'sms' AND enabled = 1)
+             THEN '1' ELSE '0' END,
+        now(), now());
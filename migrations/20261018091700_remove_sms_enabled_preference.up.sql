@@ -0,0 +1,5 @@
+-- text message channels are switched on and off individually now
+UPDATE notification_channels SET enabled = 0
+    WHERE channel_type = 'sms'
+      AND (SELECT preference FROM preferences WHERE name = 'sms_enabled') = '0';
+DELETE FROM preferences WHERE name = 'sms_enabled';